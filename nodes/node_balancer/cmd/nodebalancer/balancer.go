@@ -6,6 +6,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Main variable of pool of blockchains which contains pool of nodes
@@ -26,14 +28,37 @@ var blockchainPool BlockchainPool
 // Endpoint for geth/bor/etc node http.server endpoint
 type Node struct {
 	Endpoint *url.URL
+	// WebSocket endpoint, nil if node does not serve WebSocket
+	WSEndpoint *url.URL
 
 	Alive        bool
 	CurrentBlock uint64
 	CallCounter  uint64
 
+	// Share of requests relative to other nodes of blockchain
+	Weight int
+	// Node roles to route requests with required tags
+	Tags []string
+
 	mux sync.RWMutex
 
 	GethReverseProxy *httputil.ReverseProxy
+
+	// Transport shared by proxy and health checks with node TLS configuration
+	transport *http.Transport
+	connKey   string
+
+	// Hostname of node and its resolved addresses, static nodes are
+	// resolved only once at creation
+	hostname  string
+	static    bool
+	addresses []string
+
+	// Health check settings and state
+	health          HealthConfig
+	lastHealthCheck time.Time
+	failedChecks    int
+	passedChecks    int
 }
 
 type NodePool struct {
@@ -46,6 +71,8 @@ type NodePool struct {
 
 type BlockchainPool struct {
 	Blockchains []*NodePool
+
+	mux sync.RWMutex
 }
 
 // Node status response struct for HealthCheck
@@ -53,12 +80,41 @@ type NodeStatusResultResponse struct {
 	Number string `json:"number"`
 }
 
+// Result is a block object, hex block number or block number depends
+// on health check method
 type NodeStatusResponse struct {
-	Result NodeStatusResultResponse `json:"result"`
+	Result json.RawMessage `json:"result"`
+}
+
+// blockNumber parses block number from result of health check method
+func (r NodeStatusResponse) blockNumber() (uint64, error) {
+	var blockNumberHex string
+	var result NodeStatusResultResponse
+	var blockNumber uint64
+	if err := json.Unmarshal(r.Result, &result); err == nil {
+		blockNumberHex = result.Number
+	} else if err := json.Unmarshal(r.Result, &blockNumberHex); err != nil {
+		err = json.Unmarshal(r.Result, &blockNumber)
+		return blockNumber, err
+	}
+
+	return strconv.ParseUint(strings.Replace(blockNumberHex, "0x", "", -1), 16, 64)
+}
+
+// healthCheckRequest returns JSON RPC request of health check method
+func healthCheckRequest(method string) ([]byte, error) {
+	params := []interface{}{}
+	if method == DefaultHealthCheckMethod {
+		params = []interface{}{"latest", false}
+	}
+	return json.Marshal(JSONRPCRequest{Jsonrpc: "2.0", Method: method, Params: params, ID: 1})
 }
 
 // AddNode to the nodes pool
 func (bpool *BlockchainPool) AddNode(node *Node, blockchain string) {
+	bpool.mux.Lock()
+	defer bpool.mux.Unlock()
+
 	var nodePool *NodePool
 	for _, b := range bpool.Blockchains {
 		if b.Blockchain == blockchain {
@@ -78,10 +134,78 @@ func (bpool *BlockchainPool) AddNode(node *Node, blockchain string) {
 	}
 }
 
+// ReplaceNodePools atomically replaces all node pools and returns nodes
+// which are not present in new pools anymore
+func (bpool *BlockchainPool) ReplaceNodePools(nodePools []*NodePool) []*Node {
+	bpool.mux.Lock()
+	oldNodePools := bpool.Blockchains
+	bpool.Blockchains = nodePools
+	bpool.mux.Unlock()
+
+	currentNodes := make(map[*Node]bool)
+	for _, b := range nodePools {
+		for _, n := range b.Nodes {
+			currentNodes[n] = true
+		}
+	}
+	var removedNodes []*Node
+	for _, b := range oldNodePools {
+		for _, n := range b.Nodes {
+			if !currentNodes[n] {
+				removedNodes = append(removedNodes, n)
+			}
+		}
+	}
+
+	return removedNodes
+}
+
+// FindNode returns node of blockchain with provided endpoint if it exists in pool
+func (bpool *BlockchainPool) FindNode(blockchain, endpoint string) *Node {
+	bpool.mux.RLock()
+	defer bpool.mux.RUnlock()
+
+	for _, b := range bpool.Blockchains {
+		if b.Blockchain != blockchain {
+			continue
+		}
+		for _, n := range b.Nodes {
+			if n.Endpoint.String() == endpoint {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// snapshot returns current list of node pools, safe to iterate without lock
+// because pools are replaced but never modified after publishing
+func (bpool *BlockchainPool) snapshot() []*NodePool {
+	bpool.mux.RLock()
+	defer bpool.mux.RUnlock()
+
+	return bpool.Blockchains
+}
+
+// httpURL returns endpoint with http scheme for WebSocket endpoints,
+// because JSON RPC calls and WebSocket handshake are sent over HTTP
+func httpURL(endpoint *url.URL) *url.URL {
+	httpEndpoint := *endpoint
+	switch endpoint.Scheme {
+	case "ws":
+		httpEndpoint.Scheme = "http"
+	case "wss":
+		httpEndpoint.Scheme = "https"
+	}
+	return &httpEndpoint
+}
+
 // SetAlive with mutex for exact node
 func (node *Node) SetAlive(alive bool) {
 	node.mux.Lock()
 	node.Alive = alive
+	node.passedChecks = 0
+	node.failedChecks = 0
 	node.mux.Unlock()
 }
 
@@ -105,6 +229,96 @@ func (node *Node) UpdateNodeState(currentBlock uint64, alive bool) (callCounter
 	return callCounter
 }
 
+// SetHealthConfig with mutex for exact node
+func (node *Node) SetHealthConfig(health HealthConfig) {
+	node.mux.Lock()
+	node.health = health
+	node.mux.Unlock()
+}
+
+// GetHealthConfig returns node health check settings
+func (node *Node) GetHealthConfig() (health HealthConfig) {
+	node.mux.RLock()
+	health = node.health
+	node.mux.RUnlock()
+	return health
+}
+
+// updateHealth records result of health check, node status is changed after
+// number of sequential checks with the same result reaches threshold
+func (node *Node) updateHealth(currentBlock uint64, passed bool) (alive bool, callCounter uint64) {
+	node.mux.Lock()
+	defer node.mux.Unlock()
+
+	if passed {
+		node.passedChecks++
+		node.failedChecks = 0
+		node.CurrentBlock = currentBlock
+		if !node.Alive && node.passedChecks >= node.health.HealthyThreshold {
+			node.Alive = true
+		}
+	} else {
+		node.failedChecks++
+		node.passedChecks = 0
+		if node.Alive && node.failedChecks >= node.health.UnhealthyThreshold {
+			node.Alive = false
+			node.CurrentBlock = 0
+		}
+	}
+
+	return node.Alive, node.CallCounter
+}
+
+// SetWeight with mutex for exact node
+func (node *Node) SetWeight(weight int) {
+	node.mux.Lock()
+	node.Weight = weight
+	node.mux.Unlock()
+}
+
+// GetWeight returns node weight, nodes without weight are equal to weight 1
+func (node *Node) GetWeight() (weight int) {
+	node.mux.RLock()
+	weight = node.Weight
+	node.mux.RUnlock()
+	if weight < 1 {
+		weight = 1
+	}
+	return weight
+}
+
+// SetTags with mutex for exact node
+func (node *Node) SetTags(tags []string) {
+	node.mux.Lock()
+	node.Tags = tags
+	node.mux.Unlock()
+}
+
+// HasTags returns true when node able to serve request with required tags
+func (node *Node) HasTags(tags []string) (match bool) {
+	node.mux.RLock()
+	match = MatchTags(node.Tags, tags)
+	node.mux.RUnlock()
+	return match
+}
+
+// nodeIndex maps counter to index of node, each node takes
+// number of sequential counter values equal to its weight
+func (np *NodePool) nodeIndex(counter uint64) int {
+	totalWeight := 0
+	for _, n := range np.Nodes {
+		totalWeight += n.GetWeight()
+	}
+	position := int(counter % uint64(totalWeight))
+	for i, n := range np.Nodes {
+		position -= n.GetWeight()
+		if position < 0 {
+			return i
+		}
+	}
+	return 0
+}
+
 // IncreaseCallCounter increased to 1 each time node called
 func (node *Node) IncreaseCallCounter() {
 	node.mux.Lock()
@@ -117,30 +331,53 @@ func (node *Node) IncreaseCallCounter() {
 	node.mux.Unlock()
 }
 
-// GetNextNode returns next active peer to take a connection
-// Loop through entire nodes to find out an alive one
-func (bpool *BlockchainPool) GetNextNode(blockchain string) *Node {
+// HasWebSocket returns true when node serves WebSocket
+func (node *Node) HasWebSocket() bool {
+	return node.WSEndpoint != nil
+}
+
+// GetNextNode returns next active peer with required tags to take a connection
+func (bpool *BlockchainPool) GetNextNode(blockchain string, tags []string) *Node {
+	return bpool.nextNode(blockchain, func(n *Node) bool {
+		return n.HasTags(tags)
+	})
+}
+
+// GetNextWebSocketNode returns next active peer with required tags which
+// serves WebSocket, nodes without WebSocket endpoint are skipped
+func (bpool *BlockchainPool) GetNextWebSocketNode(blockchain string, tags []string) *Node {
+	return bpool.nextNode(blockchain, func(n *Node) bool {
+		return n.HasWebSocket() && n.HasTags(tags)
+	})
+}
+
+// nextNode loops through entire nodes to find out an alive one matching requirements
+func (bpool *BlockchainPool) nextNode(blockchain string, match func(n *Node) bool) *Node {
 	highestBlock := uint64(0)
 
 	// Get NodePool with correct blockchain
 	var np *NodePool
-	for _, b := range bpool.Blockchains {
+	for _, b := range bpool.snapshot() {
 		if b.Blockchain == blockchain {
 			np = b
 			for _, n := range b.Nodes {
-				if n.CurrentBlock > highestBlock {
+				if n.CurrentBlock > highestBlock && match(n) {
 					highestBlock = n.CurrentBlock
 				}
 			}
 		}
 	}
 
+	if np == nil || len(np.Nodes) == 0 {
+		return nil
+	}
+
 	// Increase Current value with 1
 	currentInc := atomic.AddUint64(&np.Current, uint64(1))
 
-	// next is an Atomic incrementer, value always in range from 0 to slice length,
-	// it returns an index of slice
-	next := int(currentInc % uint64(len(np.Nodes)))
+	// next is an index of slice chosen by Atomic incrementer according
+	// with nodes weights, so nodes with higher weight are chosen more often
+	next := np.nodeIndex(currentInc)
 
 	// Start from next one and move full cycle
 	l := len(np.Nodes) + next
@@ -148,12 +385,8 @@ func (bpool *BlockchainPool) GetNextNode(blockchain string) *Node {
 	for i := next; i < l; i++ {
 		// Take an index by modding with length
 		idx := i % len(np.Nodes)
-		// If we have an alive one, use it and store if its not the original one
-		if np.Nodes[idx].IsAlive() {
-			if i != next {
-				// Mark the current one
-				atomic.StoreUint64(&np.Current, uint64(idx))
-			}
+		// If we have an alive one matching requirements, use it
+		if np.Nodes[idx].IsAlive() && match(np.Nodes[idx]) {
 			// Pass nodes with low blocks
 			// TODO(kompotkot): Re-write to not rotate through not highest blocks
 			if np.Nodes[idx].CurrentBlock < highestBlock {
@@ -168,7 +401,7 @@ func (bpool *BlockchainPool) GetNextNode(blockchain string) *Node {
 
 // SetNodeStatus modify status of the node
 func (bpool *BlockchainPool) SetNodeStatus(url *url.URL, alive bool) {
-	for _, b := range bpool.Blockchains {
+	for _, b := range bpool.snapshot() {
 		for _, n := range b.Nodes {
 			if n.Endpoint.String() == url.String() {
 				n.SetAlive(alive)
@@ -181,7 +414,7 @@ func (bpool *BlockchainPool) SetNodeStatus(url *url.URL, alive bool) {
 // StatusLog logs node status
 // TODO(kompotkot): Print list of alive and dead nodes
 func (bpool *BlockchainPool) StatusLog() {
-	for _, b := range bpool.Blockchains {
+	for _, b := range bpool.snapshot() {
 		for _, n := range b.Nodes {
 			log.Printf(
 				"Blockchain %s node %s is alive %t. Blockchain called %d times",
@@ -191,57 +424,82 @@ func (bpool *BlockchainPool) StatusLog() {
 	}
 }
 
-// HealthCheck fetch the node latest block
+// HealthCheck fetch the latest block of all nodes
 func (bpool *BlockchainPool) HealthCheck() {
-	for _, b := range bpool.Blockchains {
+	for _, b := range bpool.snapshot() {
 		for _, n := range b.Nodes {
-			alive := false
+			n.healthCheck(time.Now())
+		}
+	}
+}
 
-			httpClient := http.Client{Timeout: NB_HEALTH_CHECK_CALL_TIMEOUT}
-			resp, err := httpClient.Post(
-				n.Endpoint.String(),
-				"application/json",
-				bytes.NewBuffer([]byte(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["latest", false],"id":1}`)),
-			)
-			if err != nil {
-				n.UpdateNodeState(0, alive)
-				log.Printf("Unable to reach node: %s", n.Endpoint.Host)
-				continue
+// HealthCheckDue checks nodes which health check interval elapsed
+func (bpool *BlockchainPool) HealthCheckDue(now time.Time) {
+	for _, b := range bpool.snapshot() {
+		for _, n := range b.Nodes {
+			n.mux.RLock()
+			due := now.Sub(n.lastHealthCheck) >= time.Duration(n.health.Interval)
+			n.mux.RUnlock()
+			if due {
+				n.healthCheck(now)
 			}
-			defer resp.Body.Close()
+		}
+	}
+}
 
-			body, err := ioutil.ReadAll(resp.Body)
-			if err != nil {
-				n.UpdateNodeState(0, alive)
-				log.Printf("Unable to parse response from %s node, err %v", n.Endpoint.Host, err)
-				continue
-			}
+// healthCheck fetch the node latest block with node health check settings
+func (n *Node) healthCheck(now time.Time) {
+	health := n.GetHealthConfig()
+	n.mux.Lock()
+	n.lastHealthCheck = now
+	n.mux.Unlock()
+
+	blockNumber, err := n.fetchBlockNumber(health)
+	if err != nil {
+		alive, _ := n.updateHealth(0, false)
+		log.Printf("Node %s is alive: %t, health check failed, err: %v", n.Endpoint.Host, alive, err)
+		return
+	}
 
-			var statusResponse NodeStatusResponse
-			err = json.Unmarshal(body, &statusResponse)
-			if err != nil {
-				n.UpdateNodeState(0, alive)
-				log.Printf("Unable to read json response from %s node, err: %v", n.Endpoint.Host, err)
-				continue
-			}
+	// Mark node in list of pool as alive and update current block
+	alive, callCounter := n.updateHealth(blockNumber, blockNumber != 0)
 
-			blockNumberHex := strings.Replace(statusResponse.Result.Number, "0x", "", -1)
-			blockNumber, err := strconv.ParseUint(blockNumberHex, 16, 64)
-			if err != nil {
-				n.UpdateNodeState(0, alive)
-				log.Printf("Unable to parse block number from hex to string, err: %v", err)
-				continue
-			}
+	log.Printf(
+		"Node %s is alive: %t with current block: %d called: %d times", n.Endpoint.Host, alive, blockNumber, callCounter,
+	)
+}
 
-			// Mark node in list of pool as alive and update current block
-			if blockNumber != 0 {
-				alive = true
-			}
-			callCounter := n.UpdateNodeState(blockNumber, alive)
+func (n *Node) fetchBlockNumber(health HealthConfig) (uint64, error) {
+	requestBody, err := healthCheckRequest(health.Method)
+	if err != nil {
+		return 0, err
+	}
 
-			log.Printf(
-				"Node %s is alive: %t with current block: %d called: %d times", n.Endpoint.Host, alive, blockNumber, callCounter,
-			)
-		}
+	httpClient := http.Client{Timeout: time.Duration(health.CallTimeout)}
+	if n.transport != nil {
+		httpClient.Transport = n.transport
+	}
+	resp, err := httpClient.Post(httpURL(n.Endpoint).String(), "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return 0, fmt.Errorf("unable to reach node, err: %v", err)
 	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read response, err: %v", err)
+	}
+
+	var statusResponse NodeStatusResponse
+	err = json.Unmarshal(body, &statusResponse)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse json response, err: %v", err)
+	}
+
+	blockNumber, err := statusResponse.blockNumber()
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse block number, err: %v", err)
+	}
+
+	return blockNumber, nil
 }
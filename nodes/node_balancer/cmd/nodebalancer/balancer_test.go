@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetNextNodeWeights(t *testing.T) {
+	heavyNode := &Node{Alive: true, Weight: 3}
+	lightNode := &Node{Alive: true, Weight: 1}
+	deadNode := &Node{Alive: false, Weight: 2}
+
+	var cases = []struct {
+		nodes    []*Node
+		expected map[*Node]int
+	}{
+		{[]*Node{heavyNode, lightNode}, map[*Node]int{heavyNode: 300, lightNode: 100}},
+		{[]*Node{{Alive: true}, {Alive: true}}, nil},
+		// Requests of dead node are passed to next alive one
+		{[]*Node{deadNode, lightNode}, map[*Node]int{lightNode: 400}},
+	}
+	for i, c := range cases {
+		bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: c.nodes}}}
+		calls := make(map[*Node]int)
+		for j := 0; j < 400; j++ {
+			calls[bpool.GetNextNode("ethereum", nil)]++
+		}
+		if c.expected == nil {
+			// Nodes without weights are equal
+			c.expected = map[*Node]int{c.nodes[0]: 200, c.nodes[1]: 200}
+		}
+		for node, expectedCalls := range c.expected {
+			if calls[node] != expectedCalls {
+				t.Fatalf("Case %d: expected %d calls of node, got %d", i, expectedCalls, calls[node])
+			}
+		}
+	}
+}
+
+func TestGetNextNodeTags(t *testing.T) {
+	defaultNode := &Node{Alive: true}
+	archiveNode := &Node{Alive: true, Tags: []string{"archive"}}
+	bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: []*Node{defaultNode, archiveNode}}}}
+
+	var cases = []struct {
+		tags     []string
+		expected *Node
+	}{
+		{nil, defaultNode},
+		{[]string{"archive"}, archiveNode},
+		{[]string{"tracing"}, nil},
+	}
+	for _, c := range cases {
+		for i := 0; i < 4; i++ {
+			if node := bpool.GetNextNode("ethereum", c.tags); node != c.expected {
+				t.Fatalf("Wrong node returned for tags %v", c.tags)
+			}
+		}
+	}
+}
+
+func TestGetNextWebSocketNode(t *testing.T) {
+	wsEndpoint, _ := url.Parse("ws://10.0.0.5:8546")
+	httpNode := &Node{Alive: true}
+	wsNode := &Node{Alive: true, WSEndpoint: wsEndpoint}
+	archiveNode := &Node{Alive: true, WSEndpoint: wsEndpoint, Tags: []string{"archive"}}
+	bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: []*Node{httpNode, wsNode, archiveNode}}}}
+
+	var cases = []struct {
+		tags     []string
+		expected *Node
+	}{
+		{nil, wsNode},
+		{[]string{"archive"}, archiveNode},
+	}
+	for _, c := range cases {
+		for i := 0; i < 6; i++ {
+			if node := bpool.GetNextWebSocketNode("ethereum", c.tags); node != c.expected {
+				t.Fatalf("Wrong WebSocket node returned for tags %v", c.tags)
+			}
+		}
+	}
+
+	wsNode.SetAlive(false)
+	if node := bpool.GetNextWebSocketNode("ethereum", nil); node != nil {
+		t.Fatal("Expected no WebSocket node when node is not alive")
+	}
+	if node := bpool.GetNextNode("ethereum", nil); node != httpNode {
+		t.Fatal("Expected node without WebSocket for HTTP requests")
+	}
+}
+
+func TestHealthCheckThresholds(t *testing.T) {
+	var mux sync.Mutex
+	response := `{"jsonrpc":"2.0","id":1,"result":"0x10"}`
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request JSONRPCRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		mux.Lock()
+		defer mux.Unlock()
+		method = request.Method
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	nodeConfig := NodeConfig{Blockchain: "ethereum", Endpoint: server.URL}
+	nodeConfig.complete()
+	node, err := newNode(nodeConfig)
+	if err != nil {
+		t.Fatalf("Unable to create node, err: %v", err)
+	}
+	node.SetHealthConfig(DefaultHealthConfig().override(&HealthConfig{
+		Interval: ConfigDuration(time.Minute), Method: "eth_blockNumber", UnhealthyThreshold: 2, HealthyThreshold: 3,
+	}))
+	bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: []*Node{node}}}}
+
+	var cases = []struct {
+		response string
+		alive    bool
+		block    uint64
+	}{
+		{`{"jsonrpc":"2.0","id":1,"result":"0x10"}`, true, 16},
+		// Node is marked as dead after two failed checks
+		{`{"jsonrpc":"2.0","id":1,"error":{"code":-32000}}`, true, 16},
+		{`{"jsonrpc":"2.0","id":1,"error":{"code":-32000}}`, false, 0},
+		// And alive after three passed checks
+		{`{"jsonrpc":"2.0","id":1,"result":"0x11"}`, false, 17},
+		{`{"jsonrpc":"2.0","id":1,"result":"0x12"}`, false, 18},
+		{`{"jsonrpc":"2.0","id":1,"result":"0x13"}`, true, 19},
+		// Block number results of other methods
+		{`{"jsonrpc":"2.0","id":1,"result":{"number":"0x14"}}`, true, 20},
+		{`{"jsonrpc":"2.0","id":1,"result":21}`, true, 21},
+	}
+	for i, c := range cases {
+		mux.Lock()
+		response = c.response
+		mux.Unlock()
+		bpool.HealthCheck()
+		if node.IsAlive() != c.alive || node.CurrentBlock != c.block {
+			t.Fatalf("Case %d: expected alive %t at block %d, got %t at %d", i, c.alive, c.block, node.IsAlive(), node.CurrentBlock)
+		}
+	}
+	if method != "eth_blockNumber" {
+		t.Fatalf("Expected health check with eth_blockNumber, got %s", method)
+	}
+
+	// Node is not checked until its interval elapsed
+	bpool.HealthCheckDue(time.Now().Add(time.Second))
+	mux.Lock()
+	response = `{"jsonrpc":"2.0","id":1,"result":"0x20"}`
+	mux.Unlock()
+	bpool.HealthCheckDue(time.Now().Add(time.Second))
+	if node.CurrentBlock != 21 {
+		t.Fatalf("Expected node not checked before interval, got block %d", node.CurrentBlock)
+	}
+	bpool.HealthCheckDue(time.Now().Add(2 * time.Minute))
+	if node.CurrentBlock != 32 {
+		t.Fatalf("Expected node checked after interval, got block %d", node.CurrentBlock)
+	}
+}
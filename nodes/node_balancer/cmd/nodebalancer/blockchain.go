@@ -46,8 +46,57 @@ type JSONRPCRequest struct {
 	ID      uint64        `json:"id"`
 }
 
+// Settings shared by nodes of blockchain, port, schemes and WebSocket
+// port are defaults for nodes which do not specify them
 type BlockchainConfig struct {
-	Blockchain string
+	Blockchain string `json:"-"`
 	IPs        []string
-	Port       string
+
+	Port     int    `json:"port,omitempty"`
+	Scheme   string `json:"scheme,omitempty"`
+	WSPort   int    `json:"ws_port,omitempty"`
+	WSScheme string `json:"ws_scheme,omitempty"`
+
+	Health *HealthConfig `json:"health,omitempty"`
+}
+
+// Default settings of blockchains, used if not overridden at blockchains
+// settings of configuration
+var DEFAULT_BLOCKCHAIN_CONFIGS = map[string]BlockchainConfig{
+	"ethereum": {Blockchain: "ethereum", Port: 8545},
+	"polygon":  {Blockchain: "polygon", Port: 8545},
+	"xdai":     {Blockchain: "xdai", Port: 8545},
+}
+
+// override returns settings with fields replaced by specified fields of other
+func (bc BlockchainConfig) override(other BlockchainConfig) BlockchainConfig {
+	if other.Port != 0 {
+		bc.Port = other.Port
+	}
+	if other.Scheme != "" {
+		bc.Scheme = other.Scheme
+	}
+	if other.WSPort != 0 {
+		bc.WSPort = other.WSPort
+	}
+	if other.WSScheme != "" {
+		bc.WSScheme = other.WSScheme
+	}
+	if other.Health != nil {
+		bc.Health = other.Health
+	}
+	return bc
+}
+
+// blockchainDefaults returns default settings of blockchain nodes from
+// configuration settings and built-in defaults
+func blockchainDefaults(blockchains map[string]BlockchainConfig, blockchain string) BlockchainConfig {
+	defaults := BlockchainConfig{Blockchain: blockchain}
+	if builtin, ok := DEFAULT_BLOCKCHAIN_CONFIGS[blockchain]; ok {
+		defaults = defaults.override(builtin)
+	}
+	if configured, ok := blockchains[blockchain]; ok {
+		defaults = defaults.override(configured)
+	}
+	return defaults
 }
@@ -37,6 +37,7 @@ type StateCLI struct {
 	deleteAccessCmd   *flag.FlagSet
 	serverCmd         *flag.FlagSet
 	usersCmd          *flag.FlagSet
+	validateCmd       *flag.FlagSet
 	versionCmd        *flag.FlagSet
 
 	// Common flags
@@ -56,22 +57,26 @@ type StateCLI struct {
 	listeningPortFlag     string
 	enableHealthCheckFlag bool
 	enableDebugFlag       bool
+	strictConfigFlag      bool
 
 	// Users list flags
 	limitFlag  int
 	offsetFlag int
+
+	// Validate flags
+	jsonFlag bool
 }
 
 func (s *StateCLI) usage() {
-	fmt.Printf(`usage: nodebalancer [-h] {%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s} ...
+	fmt.Printf(`usage: nodebalancer [-h] {%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s,%[7]s} ...
 
 Moonstream node balancer CLI
 optional arguments:
     -h, --help         show this help message and exit
 
 subcommands:
-    {%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s}
-`, s.addAccessCmd.Name(), s.generateConfigCmd.Name(), s.deleteAccessCmd.Name(), s.serverCmd.Name(), s.usersCmd.Name(), s.versionCmd.Name())
+    {%[1]s,%[2]s,%[3]s,%[4]s,%[5]s,%[6]s,%[7]s}
+`, s.addAccessCmd.Name(), s.generateConfigCmd.Name(), s.deleteAccessCmd.Name(), s.serverCmd.Name(), s.usersCmd.Name(), s.validateCmd.Name(), s.versionCmd.Name())
 }
 
 // Check if required flags are set
@@ -98,6 +103,10 @@ func (s *StateCLI) checkRequirements() {
 			fmt.Printf("List user access tokens\n\n")
 			s.usersCmd.PrintDefaults()
 			os.Exit(0)
+		case s.validateCmd.Parsed():
+			fmt.Printf("Validate environment and nodes configuration without starting server\n\n")
+			s.validateCmd.PrintDefaults()
+			os.Exit(0)
 		case s.versionCmd.Parsed():
 			fmt.Printf("Show version\n\n")
 			s.versionCmd.PrintDefaults()
@@ -144,7 +153,16 @@ func (s *StateCLI) checkRequirements() {
 		os.Exit(1)
 	}
 
-	if !config.ConfigExists {
+	if s.validateCmd.Parsed() {
+		// Validation reports missing configuration instead of generating default one
+		s.configPathFlag = config.ConfigPath
+		return
+	}
+	if !config.ConfigExists && appConfig.NodesSource == NodesSourceBugout && !s.generateConfigCmd.Parsed() {
+		log.Printf("Nodes are loaded from Bugout journal %s", appConfig.NodesJournalID)
+	} else if !config.ConfigExists && appConfig.Nodes != "" && !s.generateConfigCmd.Parsed() {
+		log.Printf("Configuration file %s not found, nodes are loaded from %s", config.ConfigPath, NodesEnvSource)
+	} else if !config.ConfigExists {
 		if err := GenerateDefaultConfig(config); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -162,10 +180,11 @@ func (s *StateCLI) populateCLI() {
 	s.deleteAccessCmd = flag.NewFlagSet("delete-access", flag.ExitOnError)
 	s.serverCmd = flag.NewFlagSet("server", flag.ExitOnError)
 	s.usersCmd = flag.NewFlagSet("users", flag.ExitOnError)
+	s.validateCmd = flag.NewFlagSet("validate", flag.ExitOnError)
 	s.versionCmd = flag.NewFlagSet("version", flag.ExitOnError)
 
 	// Common flag pointers
-	for _, fs := range []*flag.FlagSet{s.addAccessCmd, s.generateConfigCmd, s.deleteAccessCmd, s.serverCmd, s.usersCmd, s.versionCmd} {
+	for _, fs := range []*flag.FlagSet{s.addAccessCmd, s.generateConfigCmd, s.deleteAccessCmd, s.serverCmd, s.usersCmd, s.validateCmd, s.versionCmd} {
 		fs.BoolVar(&s.helpFlag, "help", false, "Show help message")
 		fs.StringVar(&s.configPathFlag, "config", "", "Path to configuration file (default: ~/.nodebalancer/config.txt)")
 	}
@@ -184,15 +203,38 @@ func (s *StateCLI) populateCLI() {
 
 	// Server subcommand flag pointers
 	s.serverCmd.StringVar(&s.listeningAddrFlag, "host", "127.0.0.1", "Server listening address")
-	s.serverCmd.StringVar(&s.listeningPortFlag, "port", "8544", "Server listening port")
+	s.serverCmd.StringVar(&s.listeningPortFlag, "port", "", "Server listening port (default: NB_SERVER_PORT or 8544)")
 	s.serverCmd.BoolVar(&s.enableHealthCheckFlag, "healthcheck", false, "To enable healthcheck set healthcheck flag")
 	s.serverCmd.BoolVar(&s.enableDebugFlag, "debug", false, "To enable debug mode with extended log set debug flag")
+	// Server and validate subcommands flag pointers
+	for _, fs := range []*flag.FlagSet{s.serverCmd, s.validateCmd} {
+		fs.BoolVar(&s.strictConfigFlag, "strict", false, "Fail on nodes configuration warnings, like unknown blockchain names")
+	}
+
+	// Validate subcommand flag pointers
+	s.validateCmd.BoolVar(&s.jsonFlag, "json", false, "Print summary as JSON")
 
 	// Users list subcommand flag pointers
 	s.usersCmd.IntVar(&s.limitFlag, "limit", 10, "Output result limit")
 	s.usersCmd.IntVar(&s.offsetFlag, "offset", 0, "Result output offset")
 }
 
+// Environment variables used by access management subcommands
+var accessEnvVars = []string{"NB_APPLICATION_ID", "NB_CONTROLLER_TOKEN"}
+
+// exitOnConfigErrors prints errors of environment variables with provided
+// names or all errors if names are not passed and exits
+func exitOnConfigErrors(envErr error, names ...string) {
+	errs, _ := envErr.(ConfigErrors)
+	if len(names) > 0 {
+		errs = errs.Filter(names...)
+	}
+	if len(errs) > 0 {
+		fmt.Println(errs)
+		os.Exit(1)
+	}
+}
+
 func cli() {
 	stateCLI.populateCLI()
 	if len(os.Args) < 2 {
@@ -208,11 +250,17 @@ func cli() {
 	}
 	bugoutClient = bc
 
+	// Load configuration from environment variables, subcommands fail only
+	// on errors of variables they depend on
+	config, envErr := LoadConfig()
+	SetConfig(config)
+
 	// Parse subcommands and appropriate FlagSet
 	switch os.Args[1] {
 	case "add-access":
 		stateCLI.addAccessCmd.Parse(os.Args[2:])
 		stateCLI.checkRequirements()
+		exitOnConfigErrors(envErr, accessEnvVars...)
 
 		proposedUserAccess := ClientResourceData{
 			UserID:           stateCLI.userIDFlag,
@@ -223,17 +271,17 @@ func cli() {
 			ExtendedMethods:  stateCLI.extendedMethodsFlag,
 		}
 		_, err := bugoutClient.Brood.FindUser(
-			NB_CONTROLLER_TOKEN,
+			appConfig.ControllerToken,
 			map[string]string{
 				"user_id":        proposedUserAccess.UserID,
-				"application_id": NB_APPLICATION_ID,
+				"application_id": appConfig.ApplicationID,
 			},
 		)
 		if err != nil {
 			fmt.Printf("User does not exists, err: %v\n", err)
 			os.Exit(1)
 		}
-		resource, err := bugoutClient.Brood.CreateResource(NB_CONTROLLER_TOKEN, NB_APPLICATION_ID, proposedUserAccess)
+		resource, err := bugoutClient.Brood.CreateResource(appConfig.ControllerToken, appConfig.ApplicationID, proposedUserAccess)
 		if err != nil {
 			fmt.Printf("Unable to create user access, err: %v\n", err)
 			os.Exit(1)
@@ -252,6 +300,7 @@ func cli() {
 	case "delete-access":
 		stateCLI.deleteAccessCmd.Parse(os.Args[2:])
 		stateCLI.checkRequirements()
+		exitOnConfigErrors(envErr, accessEnvVars...)
 
 		queryParameters := make(map[string]string)
 		if stateCLI.userIDFlag != "" {
@@ -261,8 +310,8 @@ func cli() {
 			queryParameters["access_id"] = stateCLI.accessIDFlag
 		}
 		resources, err := bugoutClient.Brood.GetResources(
-			NB_CONTROLLER_TOKEN,
-			NB_APPLICATION_ID,
+			appConfig.ControllerToken,
+			appConfig.ApplicationID,
 			queryParameters,
 		)
 		if err != nil {
@@ -272,7 +321,7 @@ func cli() {
 
 		var userAccesses []ClientResourceData
 		for _, resource := range resources.Resources {
-			deletedResource, err := bugoutClient.Brood.DeleteResource(NB_CONTROLLER_TOKEN, resource.Id)
+			deletedResource, err := bugoutClient.Brood.DeleteResource(appConfig.ControllerToken, resource.Id)
 			if err != nil {
 				fmt.Printf("Unable to delete resource %s, err: %v\n", resource.Id, err)
 				continue
@@ -301,14 +350,14 @@ func cli() {
 	case "server":
 		stateCLI.serverCmd.Parse(os.Args[2:])
 		stateCLI.checkRequirements()
-
-		CheckEnvVarSet()
+		exitOnConfigErrors(envErr)
 
 		Server()
 
 	case "users":
 		stateCLI.usersCmd.Parse(os.Args[2:])
 		stateCLI.checkRequirements()
+		exitOnConfigErrors(envErr, accessEnvVars...)
 
 		var queryParameters map[string]string
 		if stateCLI.userIDFlag != "" {
@@ -318,8 +367,8 @@ func cli() {
 			queryParameters["access_id"] = stateCLI.accessIDFlag
 		}
 		resources, err := bugoutClient.Brood.GetResources(
-			NB_CONTROLLER_TOKEN,
-			NB_APPLICATION_ID,
+			appConfig.ControllerToken,
+			appConfig.ApplicationID,
 			queryParameters,
 		)
 		if err != nil {
@@ -359,6 +408,25 @@ func cli() {
 		}
 		fmt.Println(string(userAccessesJson))
 
+	case "validate":
+		stateCLI.validateCmd.Parse(os.Args[2:])
+		stateCLI.checkRequirements()
+
+		summary := ValidateConfiguration(stateCLI.configPathFlag, stateCLI.strictConfigFlag, envErr)
+		if stateCLI.jsonFlag {
+			summaryJson, err := json.MarshalIndent(summary, "", "  ")
+			if err != nil {
+				fmt.Printf("Unable to marshal validation summary, err: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(summaryJson))
+		} else {
+			fmt.Print(summary.Text())
+		}
+		if !summary.Valid {
+			os.Exit(1)
+		}
+
 	case "version":
 		stateCLI.versionCmd.Parse(os.Args[2:])
 		stateCLI.checkRequirements()
@@ -7,7 +7,8 @@ import (
 )
 
 var (
-	clientPool map[string]ClientPool
+	clientPool    map[string]ClientPool
+	clientPoolMux sync.RWMutex
 )
 
 // Structure to define user access according with Brood resources
@@ -40,27 +41,43 @@ type ClientPool struct {
 
 // Generate pools for clients for different blockchains
 func CreateClientPools() {
-	clientPool = make(map[string]ClientPool)
+	newClientPool := make(map[string]ClientPool)
+	for b := range GetConfigBlockchains() {
+		newClientPool[b] = ClientPool{Client: make(map[string]*Client)}
+	}
+
+	clientPoolMux.Lock()
+	clientPool = newClientPool
+	clientPoolMux.Unlock()
+}
+
+// Add pools for new blockchains and remove pools of blockchains not
+// presented in configuration anymore, existing pools are preserved
+func UpdateClientPools() {
+	clientPoolMux.Lock()
+	defer clientPoolMux.Unlock()
 
-	for b := range configBlockchains {
-		clientPool[b] = ClientPool{}
+	newClientPool := make(map[string]ClientPool)
+	for b := range GetConfigBlockchains() {
 		if cp, ok := clientPool[b]; ok {
-			cp.Client = make(map[string]*Client)
-			clientPool[b] = cp
+			newClientPool[b] = cp
+		} else {
+			newClientPool[b] = ClientPool{Client: make(map[string]*Client)}
 		}
 	}
+	clientPool = newClientPool
 }
 
 // Return client pool corresponding to provided blockchain
 func GetClientPool(blockchain string) *ClientPool {
-	var cpool *ClientPool
-	for b := range configBlockchains {
-		if b == blockchain {
-			c := clientPool[blockchain]
-			cpool = &c
-		}
+	clientPoolMux.RLock()
+	defer clientPoolMux.RUnlock()
+
+	c, ok := clientPool[blockchain]
+	if !ok {
+		return nil
 	}
-	return cpool
+	return &c
 }
 
 // Updates client last appeal to node
@@ -102,7 +119,7 @@ func (cpool *ClientPool) AddClientNode(id string, node *Node) {
 func (cpool *ClientPool) GetClientNode(id string) *Node {
 	if cpool.Client[id] != nil {
 		lastCallTs := cpool.Client[id].GetClientLastCallDiff()
-		if lastCallTs < NB_CLIENT_NODE_KEEP_ALIVE {
+		if lastCallTs < int64(appConfig.ClientNodeKeepAlive.Seconds()) {
 			cpool.Client[id].UpdateClientLastCall()
 			return cpool.Client[id].Node
 		}
@@ -117,7 +134,7 @@ func (cpool *ClientPool) CleanInactiveClientNodes() int {
 	cnt := 0
 	for id, client := range cpool.Client {
 		lastCallTs := client.GetClientLastCallDiff()
-		if lastCallTs >= NB_CLIENT_NODE_KEEP_ALIVE {
+		if lastCallTs >= int64(appConfig.ClientNodeKeepAlive.Seconds()) {
 			delete(cpool.Client, id)
 		} else {
 			cnt += 1
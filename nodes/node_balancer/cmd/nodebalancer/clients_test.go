@@ -14,8 +14,10 @@ func TestAddClientNode(t *testing.T) {
 	}{
 		{map[string]*Client{"1": {Node: &Node{Alive: true}}}, "1"},
 	}
+	configBlockchains = map[string]bool{"ethereum": true}
 	for _, c := range cases {
 		CreateClientPools()
+		ethereumClientPool := clientPool["ethereum"]
 		for id, client := range c.clients {
 			ethereumClientPool.AddClientNode(id, client.Node)
 		}
@@ -41,8 +43,10 @@ func TestGetClientNode(t *testing.T) {
 		{map[string]*Client{"2": {LastCallTs: ts, Node: &Node{Alive: true}}}, "1", nil},
 		{map[string]*Client{"1": {LastCallTs: ts - NB_CLIENT_NODE_KEEP_ALIVE, Node: &Node{Alive: true}}}, "1", nil},
 	}
+	configBlockchains = map[string]bool{"ethereum": true}
 	for _, c := range cases {
 		CreateClientPools()
+		ethereumClientPool := clientPool["ethereum"]
 		for id, client := range c.clients {
 			ethereumClientPool.Client[id] = client
 		}
@@ -70,8 +74,10 @@ func TestCleanInactiveClientNodes(t *testing.T) {
 			"3": {LastCallTs: ts},
 		}, "3"},
 	}
+	configBlockchains = map[string]bool{"ethereum": true}
 	for _, c := range cases {
 		CreateClientPools()
+		ethereumClientPool := clientPool["ethereum"]
 		for id, client := range c.clients {
 			ethereumClientPool.Client[id] = client
 		}
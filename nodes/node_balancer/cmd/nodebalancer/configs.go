@@ -4,80 +4,1342 @@ Configurations for load balancer server.
 package main
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	nodeConfigs []NodeConfig
+	nodeConfigList    *NodeConfigList
+	nodeConfigListMux sync.RWMutex
 
-	// Bugout and application configuration
-	BUGOUT_AUTH_URL          = os.Getenv("BUGOUT_AUTH_URL")
-	BUGOUT_AUTH_CALL_TIMEOUT = time.Second * 5
-	NB_APPLICATION_ID        = os.Getenv("NB_APPLICATION_ID")
-	NB_CONTROLLER_TOKEN      = os.Getenv("NB_CONTROLLER_TOKEN")
-	NB_CONTROLLER_ACCESS_ID  = os.Getenv("NB_CONTROLLER_ACCESS_ID")
+	// Configuration from environment variables, set with SetConfig at start
+	appConfig Config
 
-	NB_CONNECTION_RETRIES          = 2
-	NB_CONNECTION_RETRIES_INTERVAL = time.Millisecond * 10
-	NB_HEALTH_CHECK_INTERVAL       = time.Second * 5
-	NB_HEALTH_CHECK_CALL_TIMEOUT   = time.Second * 2
+	// Health checks and access ID cache settings
+	NB_HEALTH_CHECK_INTERVAL     = time.Second * 5
+	NB_HEALTH_CHECK_TICK         = time.Second
+	NB_HEALTH_CHECK_CALL_TIMEOUT = time.Second * 2
 
 	NB_CACHE_CLEANING_INTERVAL  = time.Second * 10
 	NB_CACHE_ACCESS_ID_LIFETIME = int64(120)
 
 	NB_MAX_COUNTER_NUMBER = uint64(10000000)
 
+	NB_DNS_LOOKUP_TIMEOUT = time.Second * 5
+)
+
+// Deprecated: variables below mirror fields of Config and are kept for
+// compatibility, use appConfig instead. Values are set by SetConfig.
+var (
+	// Bugout and application configuration
+	BUGOUT_AUTH_URL          string
+	BUGOUT_AUTH_CALL_TIMEOUT time.Duration
+	NB_APPLICATION_ID        string
+	NB_CONTROLLER_TOKEN      string
+	NB_CONTROLLER_ACCESS_ID  string
+
+	NB_CONNECTION_RETRIES          int
+	NB_CONNECTION_RETRIES_INTERVAL time.Duration
+
 	// Client configuration
-	NB_CLIENT_NODE_KEEP_ALIVE = int64(5) // How long to store node in hot list for client in seconds
+	NB_CLIENT_NODE_KEEP_ALIVE int64 // How long to store node in hot list for client in seconds
 
-	NB_ACCESS_ID_HEADER   = os.Getenv("NB_ACCESS_ID_HEADER")
-	NB_DATA_SOURCE_HEADER = os.Getenv("NB_DATA_SOURCE_HEADER")
+	NB_ACCESS_ID_HEADER   string
+	NB_DATA_SOURCE_HEADER string
 
 	// Humbug configuration
-	HUMBUG_REPORTER_NB_TOKEN = os.Getenv("HUMBUG_REPORTER_NB_TOKEN")
+	HUMBUG_REPORTER_NB_TOKEN string
 
 	// Database configuration
-	MOONSTREAM_DB_URI_READ_ONLY         = os.Getenv("MOONSTREAM_DB_URI_READ_ONLY")
-	MOONSTREAM_DB_MAX_IDLE_CONNS    int = 30
-	MOONSTREAM_DB_CONN_MAX_LIFETIME     = 30 * time.Minute
+	MOONSTREAM_DB_URI_READ_ONLY     string
+	MOONSTREAM_DB_MAX_IDLE_CONNS    int
+	MOONSTREAM_DB_CONN_MAX_LIFETIME time.Duration
 )
 
-func CheckEnvVarSet() {
-	if NB_ACCESS_ID_HEADER == "" {
-		NB_ACCESS_ID_HEADER = "x-node-balancer-access-id"
+func init() {
+	SetConfig(DefaultConfig())
+}
+
+// Default names of request headers
+const (
+	DefaultAccessIDHeader   = "x-node-balancer-access-id"
+	DefaultDataSourceHeader = "x-node-balancer-data-source"
+	DefaultNodeTagsHeader   = "x-node-balancer-node-tags"
+)
+
+// Default port of node balancer server
+const DefaultServerPort = 8544
+
+// durationFromEnv parses duration like 30s from environment variable,
+// default value is returned if variable not set or invalid
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	durationRaw := os.Getenv(name)
+	if durationRaw == "" {
+		return defaultValue, nil
 	}
-	if NB_DATA_SOURCE_HEADER == "" {
-		NB_DATA_SOURCE_HEADER = "x-node-balancer-data-source"
+	duration, err := time.ParseDuration(durationRaw)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s %s should be a duration like 250ms or 2s, err: %v", name, durationRaw, err)
+	}
+	if duration < 0 {
+		return defaultValue, fmt.Errorf("%s %s should not be negative", name, durationRaw)
+	}
+	return duration, nil
+}
+
+// Config is node balancer configuration from environment variables
+type Config struct {
+	// Bugout authorization of application users and controller
+	BugoutAuthURL         string
+	BugoutAuthCallTimeout time.Duration
+	ApplicationID         string
+	ControllerToken       string
+	ControllerAccessID    string
+
+	// Number of request retries to node and interval between them
+	ConnectionRetries         int
+	ConnectionRetriesInterval time.Duration
+
+	// How long to store node in hot list for client
+	ClientNodeKeepAlive time.Duration
+
+	// Canonical names of request headers with access ID, data source and node tags
+	AccessIDHeader   string
+	DataSourceHeader string
+	NodeTagsHeader   string
+
+	// Nodes configuration passed directly, used if configuration file not found
+	Nodes string
+	// Source of nodes configuration, file (default) or bugout journal
+	NodesSource          string
+	NodesJournalID       string
+	NodesRefreshInterval time.Duration
+	// Blockchain names expected in nodes configuration
+	KnownBlockchains []string
+	// Interval of node hostnames re-resolving, zero disables re-resolving
+	DNSRefreshInterval time.Duration
+
+	HumbugReporterToken string
+
+	// Port of server if not set with flag
+	ServerPort int
+
+	Database DatabaseConfig
+}
+
+// Database connections settings, zero max open connections, lifetime
+// and idle time mean no limits
+type DatabaseConfig struct {
+	URI         string
+	URIReadOnly string
+
+	MaxIdleConns    int
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Apply sets connections pool settings to database handle
+func (dc DatabaseConfig) Apply(db *sql.DB) {
+	// Set the maximum number of concurrently idle connections,
+	// by default sql.DB allows a maximum of 2 idle connections.
+	db.SetMaxIdleConns(dc.MaxIdleConns)
+	db.SetMaxOpenConns(dc.MaxOpenConns)
+
+	// Set the maximum lifetime of a connection.
+	// Longer lifetime increase memory usage.
+	db.SetConnMaxLifetime(dc.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(dc.ConnMaxIdleTime)
+}
+
+// DefaultConfig returns configuration used if environment variables not set
+func DefaultConfig() Config {
+	return Config{
+		BugoutAuthCallTimeout: time.Second * 5,
+
+		ConnectionRetries:         2,
+		ConnectionRetriesInterval: time.Millisecond * 10,
+		ClientNodeKeepAlive:       time.Second * 5,
+
+		AccessIDHeader:   http.CanonicalHeaderKey(DefaultAccessIDHeader),
+		DataSourceHeader: http.CanonicalHeaderKey(DefaultDataSourceHeader),
+		NodeTagsHeader:   http.CanonicalHeaderKey(DefaultNodeTagsHeader),
+
+		NodesRefreshInterval: time.Minute,
+		KnownBlockchains:     []string{"ethereum", "polygon", "xdai"},
+		DNSRefreshInterval:   time.Second * 30,
+
+		ServerPort: DefaultServerPort,
+
+		Database: DatabaseConfig{
+			MaxIdleConns:    30,
+			MaxOpenConns:    0,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 0,
+		},
 	}
 }
 
-// Nodes configuration
+// LoadConfig reads configuration from environment variables, all invalid
+// variables are reported at once
+func LoadConfig() (Config, error) {
+	config := DefaultConfig()
+	var errs ConfigErrors
+
+	var err error
+	config.BugoutAuthURL = os.Getenv("BUGOUT_AUTH_URL")
+	if config.BugoutAuthURL != "" {
+		authURL, err := url.Parse(config.BugoutAuthURL)
+		if err != nil || (authURL.Scheme != "http" && authURL.Scheme != "https") || authURL.Host == "" {
+			errs = append(errs, fmt.Sprintf("BUGOUT_AUTH_URL %s should be an http or https URL", config.BugoutAuthURL))
+		}
+	}
+	config.BugoutAuthCallTimeout, err = durationFromEnvInRange(
+		"BUGOUT_AUTH_CALL_TIMEOUT", config.BugoutAuthCallTimeout, 100*time.Millisecond, time.Minute,
+	)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.ApplicationID, err = uuidFromEnv("NB_APPLICATION_ID")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.ControllerToken = os.Getenv("NB_CONTROLLER_TOKEN")
+	config.ControllerAccessID, err = uuidFromEnv("NB_CONTROLLER_ACCESS_ID")
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	config.ConnectionRetries, err = intFromEnv("NB_CONNECTION_RETRIES", config.ConnectionRetries, 1, 10)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.ConnectionRetriesInterval, err = durationFromEnvInRange(
+		"NB_CONNECTION_RETRIES_INTERVAL", config.ConnectionRetriesInterval, time.Millisecond, 30*time.Second,
+	)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.ClientNodeKeepAlive, err = durationFromEnvInRange(
+		"NB_CLIENT_NODE_KEEP_ALIVE", config.ClientNodeKeepAlive, time.Second, time.Hour,
+	)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	config.AccessIDHeader, err = headerFromEnv("NB_ACCESS_ID_HEADER", config.AccessIDHeader)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.DataSourceHeader, err = headerFromEnv("NB_DATA_SOURCE_HEADER", config.DataSourceHeader)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.NodeTagsHeader, err = headerFromEnv("NB_NODE_TAGS_HEADER", config.NodeTagsHeader)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if config.AccessIDHeader == config.DataSourceHeader || config.AccessIDHeader == config.NodeTagsHeader ||
+		config.DataSourceHeader == config.NodeTagsHeader {
+		errs = append(errs, fmt.Sprintf("NB_ACCESS_ID_HEADER, NB_DATA_SOURCE_HEADER and NB_NODE_TAGS_HEADER should differ, got %s, %s and %s",
+			config.AccessIDHeader, config.DataSourceHeader, config.NodeTagsHeader))
+	}
+
+	config.Nodes = os.Getenv("MOONSTREAM_NODES")
+	config.NodesSource = os.Getenv("MOONSTREAM_NODES_SOURCE")
+	config.NodesJournalID = os.Getenv("MOONSTREAM_NODES_JOURNAL_ID")
+	switch config.NodesSource {
+	case "", NodesSourceFile:
+	case NodesSourceBugout:
+		if config.NodesJournalID == "" {
+			errs = append(errs, fmt.Sprintf("MOONSTREAM_NODES_JOURNAL_ID should be set for %s nodes source", NodesSourceBugout))
+		}
+		if config.ControllerToken == "" {
+			errs = append(errs, fmt.Sprintf("NB_CONTROLLER_TOKEN should be set for %s nodes source", NodesSourceBugout))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("MOONSTREAM_NODES_SOURCE %s should be %s or %s", config.NodesSource, NodesSourceFile, NodesSourceBugout))
+	}
+	config.NodesRefreshInterval, err = durationFromEnv("NB_NODES_REFRESH_INTERVAL", config.NodesRefreshInterval)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if knownBlockchainsRaw := os.Getenv("NB_KNOWN_BLOCKCHAINS"); knownBlockchainsRaw != "" {
+		var blockchains []string
+		for _, b := range strings.Split(knownBlockchainsRaw, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				blockchains = append(blockchains, b)
+			}
+		}
+		if len(blockchains) == 0 {
+			errs = append(errs, fmt.Sprintf("NB_KNOWN_BLOCKCHAINS %q should be comma separated list of blockchain names", knownBlockchainsRaw))
+		} else {
+			config.KnownBlockchains = blockchains
+		}
+	}
+	config.DNSRefreshInterval, err = durationFromEnv("NB_DNS_REFRESH_INTERVAL", config.DNSRefreshInterval)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	config.HumbugReporterToken = os.Getenv("HUMBUG_REPORTER_NB_TOKEN")
+
+	config.ServerPort, err = intFromEnv("NB_SERVER_PORT", config.ServerPort, 1, 65535)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	config.Database.URI = os.Getenv("MOONSTREAM_DB_URI")
+	config.Database.URIReadOnly = os.Getenv("MOONSTREAM_DB_URI_READ_ONLY")
+	config.Database.MaxIdleConns, err = intFromEnv("MOONSTREAM_DB_MAX_IDLE_CONNS", config.Database.MaxIdleConns, 0, math.MaxInt32)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.Database.MaxOpenConns, err = intFromEnv("MOONSTREAM_DB_MAX_OPEN_CONNS", config.Database.MaxOpenConns, 0, math.MaxInt32)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.Database.ConnMaxLifetime, err = durationFromEnv("MOONSTREAM_DB_CONN_MAX_LIFETIME", config.Database.ConnMaxLifetime)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	config.Database.ConnMaxIdleTime, err = durationFromEnv("MOONSTREAM_DB_CONN_MAX_IDLE_TIME", config.Database.ConnMaxIdleTime)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return config, errs
+	}
+	return config, nil
+}
+
+// SetConfig sets configuration used by node balancer and deprecated
+// variables mirroring it
+func SetConfig(config Config) {
+	appConfig = config
+
+	BUGOUT_AUTH_URL = config.BugoutAuthURL
+	BUGOUT_AUTH_CALL_TIMEOUT = config.BugoutAuthCallTimeout
+	NB_APPLICATION_ID = config.ApplicationID
+	NB_CONTROLLER_TOKEN = config.ControllerToken
+	NB_CONTROLLER_ACCESS_ID = config.ControllerAccessID
+
+	NB_CONNECTION_RETRIES = config.ConnectionRetries
+	NB_CONNECTION_RETRIES_INTERVAL = config.ConnectionRetriesInterval
+	NB_CLIENT_NODE_KEEP_ALIVE = int64(config.ClientNodeKeepAlive.Seconds())
+
+	NB_ACCESS_ID_HEADER = config.AccessIDHeader
+	NB_DATA_SOURCE_HEADER = config.DataSourceHeader
+
+	HUMBUG_REPORTER_NB_TOKEN = config.HumbugReporterToken
+
+	MOONSTREAM_DB_URI_READ_ONLY = config.Database.URIReadOnly
+	MOONSTREAM_DB_MAX_IDLE_CONNS = config.Database.MaxIdleConns
+	MOONSTREAM_DB_CONN_MAX_LIFETIME = config.Database.ConnMaxLifetime
+}
+
+// uuidFromEnv returns UUID from environment variable, empty string is
+// returned if variable not set or invalid
+func uuidFromEnv(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", nil
+	}
+	if _, err := uuid.Parse(value); err != nil {
+		return "", fmt.Errorf("%s %s should be a UUID", name, value)
+	}
+	return value, nil
+}
+
+// intFromEnv parses integer from environment variable and checks it is in
+// range, default value is returned if variable not set
+func intFromEnv(name string, defaultValue, min, max int) (int, error) {
+	valueRaw := os.Getenv(name)
+	if valueRaw == "" {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(valueRaw)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s %s should be an integer", name, valueRaw)
+	}
+	if value < min || value > max {
+		return defaultValue, fmt.Errorf("%s %d should be between %d and %d", name, value, min, max)
+	}
+	return value, nil
+}
+
+// validHeaderName checks name consists of HTTP token characters
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		isAlphanumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphanumeric && !strings.ContainsRune("!#$%&'*+-.^_`|~", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerFromEnv returns canonical form of header name from environment
+// variable, default value is returned if variable not set or invalid
+func headerFromEnv(name string, defaultValue string) (string, error) {
+	header := os.Getenv(name)
+	if header == "" {
+		return defaultValue, nil
+	}
+	if !validHeaderName(header) {
+		return defaultValue, fmt.Errorf("%s %q is not a valid HTTP header name", name, header)
+	}
+	return http.CanonicalHeaderKey(header), nil
+}
+
+// durationFromEnvInRange parses duration from environment variable and checks
+// it is in range, default value is returned if variable not set
+func durationFromEnvInRange(name string, defaultValue, min, max time.Duration) (time.Duration, error) {
+	value, err := durationFromEnv(name, defaultValue)
+	if err != nil {
+		return defaultValue, err
+	}
+	if value < min || value > max {
+		return defaultValue, fmt.Errorf("%s %s should be between %s and %s", name, value, min, max)
+	}
+	return value, nil
+}
+
+// Nodes configuration. Node could be defined with full endpoint URL
+// or with address and port pair.
 type NodeConfig struct {
 	Blockchain string `json:"blockchain"`
-	Endpoint   string `json:"endpoint"`
+	Endpoint   string `json:"endpoint,omitempty"`
+
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	// One of http, https, ws or wss, inferred from endpoint or http by default
+	Scheme string `json:"scheme,omitempty"`
+
+	// WebSocket port of node serving HTTP, scheme is wss for https nodes
+	// and ws for others by default
+	WSPort   int    `json:"ws_port,omitempty"`
+	WSScheme string `json:"ws_scheme,omitempty"`
+
+	// TLS options for https and wss nodes, skipping verification is discouraged
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	CABundle           string `json:"ca_bundle,omitempty"`
+
+	// Share of requests relative to other nodes of blockchain, default 1
+	Weight int `json:"weight,omitempty"`
+
+	// Node roles like archive or tracing, tagged nodes serve only requests
+	// with required tags, except nodes tagged as default
+	Tags []string `json:"tags,omitempty"`
+
+	// Hostname resolved only at load and not refreshed periodically
+	Static bool `json:"static,omitempty"`
+
+	// Health check settings overriding blockchain and global settings
+	Health *HealthConfig `json:"health,omitempty"`
+
+	// Place of node definition in configuration, file line or JSON path
+	source string
+}
+
+// jsonFieldNames returns set of JSON keys defined by tags of structure
+func jsonFieldNames(v interface{}) map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// Supported node schemes
+var nodeSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ws":    true,
+	"wss":   true,
+}
+
+// inherit fills settings not specified for node with blockchain defaults,
+// port and scheme are inherited only by nodes defined with address
+func (nc *NodeConfig) inherit(defaults BlockchainConfig) {
+	if nc.Endpoint == "" {
+		if nc.Port == 0 {
+			nc.Port = defaults.Port
+		}
+		if nc.Scheme == "" {
+			nc.Scheme = defaults.Scheme
+		}
+	}
+	isWebSocket := nc.Scheme == "ws" || nc.Scheme == "wss" || strings.HasPrefix(nc.Endpoint, "ws://") || strings.HasPrefix(nc.Endpoint, "wss://")
+	if nc.WSPort == 0 && !isWebSocket {
+		nc.WSPort = defaults.WSPort
+		if nc.WSScheme == "" {
+			nc.WSScheme = defaults.WSScheme
+		}
+	}
+}
+
+// complete fills endpoint from scheme, address and port or scheme,
+// address and port from endpoint
+func (nc *NodeConfig) complete() error {
+	if nc.Blockchain == "" {
+		return fmt.Errorf("blockchain not specified")
+	}
+
+	switch {
+	case nc.Endpoint != "" && nc.Address != "":
+		return fmt.Errorf("only one of endpoint or address should be specified")
+	case nc.Endpoint != "":
+		endpoint, err := url.Parse(nc.Endpoint)
+		if err != nil {
+			return fmt.Errorf("unable to parse endpoint %s, err: %v", nc.Endpoint, err)
+		}
+		if endpoint.Scheme == "" || endpoint.Host == "" {
+			return fmt.Errorf("endpoint %s should contain scheme and host", nc.Endpoint)
+		}
+		if nc.Scheme != "" && nc.Scheme != endpoint.Scheme {
+			return fmt.Errorf("scheme %s differs from endpoint %s scheme", nc.Scheme, nc.Endpoint)
+		}
+		nc.Scheme = endpoint.Scheme
+		nc.Address = normalizeAddress(endpoint.Hostname())
+		if endpoint.Port() != "" {
+			nc.Port, err = strconv.Atoi(endpoint.Port())
+			if err != nil {
+				return fmt.Errorf("unable to parse port of endpoint %s, err: %v", nc.Endpoint, err)
+			}
+			if nc.Port == 0 {
+				return fmt.Errorf("port of endpoint %s should be greater than zero", nc.Endpoint)
+			}
+		}
+	case nc.Address != "":
+		nc.Address = normalizeAddress(nc.Address)
+		if nc.Port == 0 {
+			return fmt.Errorf("port for address %s not specified and %s blockchain has no default port", nc.Address, nc.Blockchain)
+		}
+		if nc.Scheme == "" {
+			nc.Scheme = "http"
+		}
+		nc.Endpoint = fmt.Sprintf("%s://%s", nc.Scheme, net.JoinHostPort(nc.Address, strconv.Itoa(nc.Port)))
+	default:
+		return fmt.Errorf("endpoint or address should be specified")
+	}
+
+	switch {
+	case nc.WSPort == 0 && nc.WSScheme != "":
+		return fmt.Errorf("ws_scheme %s specified without ws_port", nc.WSScheme)
+	case nc.WSPort != 0 && nc.WSScheme == "":
+		nc.WSScheme = "ws"
+		if nc.Scheme == "https" {
+			nc.WSScheme = "wss"
+		}
+	}
+
+	return nil
+}
+
+// normalizeAddress strips brackets of IPv6 address, so it could be passed to
+// net.JoinHostPort, and converts IP addresses to canonical form
+func normalizeAddress(address string) string {
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		address = address[1 : len(address)-1]
+	}
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// URL returns fully formed node endpoint, IPv6 addresses are enclosed in brackets
+func (nc NodeConfig) URL() string {
+	return nc.Endpoint
+}
+
+// WSURL returns WebSocket endpoint of node, it is empty if node
+// does not serve WebSocket
+func (nc NodeConfig) WSURL() string {
+	if nc.WSPort != 0 {
+		return fmt.Sprintf("%s://%s", nc.WSScheme, net.JoinHostPort(nc.Address, strconv.Itoa(nc.WSPort)))
+	}
+	if nc.Scheme == "ws" || nc.Scheme == "wss" {
+		return nc.Endpoint
+	}
+	return ""
+}
+
+// defaultSchemePorts are used by endpoints without explicit port
+var defaultSchemePorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ws":    80,
+	"wss":   443,
+}
+
+// effectivePort returns port of node, implicit port of endpoint is defined
+// by its scheme
+func (nc NodeConfig) effectivePort() int {
+	if nc.Port == 0 {
+		return defaultSchemePorts[nc.Scheme]
+	}
+	return nc.Port
+}
+
+// HasWebSocket returns true when node serves WebSocket
+func (nc NodeConfig) HasWebSocket() bool {
+	return nc.WSURL() != ""
+}
+
+// TLSConfig returns TLS configuration for node or nil if defaults are used
+func (nc NodeConfig) TLSConfig() (*tls.Config, error) {
+	if !nc.InsecureSkipVerify && nc.CABundle == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: nc.InsecureSkipVerify}
+	if nc.CABundle != "" {
+		caBundle, err := ioutil.ReadFile(nc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle %s, err: %v", nc.CABundle, err)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found at CA bundle %s", nc.CABundle)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return tlsConfig, nil
 }
 
-func LoadConfig(configPath string) error {
+// List of nodes loaded from configuration source
+type NodeConfigList struct {
+	Nodes  []NodeConfig
+	Source string
+
+	// Number of skipped malformed lines in legacy configuration format
+	MalformedLines int
+
+	// Revision of remote configuration to skip refresh without changes
+	Revision string
+
+	// Settings of blockchains from structured configuration
+	Blockchains map[string]BlockchainConfig
+}
+
+// ParseNodeConfigs reads node configurations from file. Format detected by file
+// extension: .json, .yaml/.yml or plain text with "blockchain,address,port" lines.
+// Plain text files which contain JSON (generated by previous versions) parsed as JSON.
+// In strict mode malformed lines of plain text configuration are not skipped.
+func ParseNodeConfigs(configPath string, strict bool) (*NodeConfigList, error) {
 	rawBytes, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNodeConfigs(configPath, strings.ToLower(filepath.Ext(configPath)), rawBytes, strict)
+}
+
+// Name of nodes configuration source passed with environment variable
+const NodesEnvSource = "MOONSTREAM_NODES"
+
+// ParseNodeConfigsEnv reads node configurations from value of MOONSTREAM_NODES
+// with JSON or plain text lines separated by ";".
+func ParseNodeConfigsEnv(rawNodes string, strict bool) (*NodeConfigList, error) {
+	if !isJSON([]byte(rawNodes)) {
+		rawNodes = strings.ReplaceAll(rawNodes, ";", "\n")
+	}
+
+	return parseNodeConfigs(NodesEnvSource, "", []byte(rawNodes), strict)
+}
+
+// isJSON checks content looks like JSON list or object
+func isJSON(rawBytes []byte) bool {
+	trimmedBytes := bytes.TrimLeft(rawBytes, " \t\r\n")
+	return len(trimmedBytes) > 0 && (trimmedBytes[0] == '[' || trimmedBytes[0] == '{')
+}
+
+// parseNodeConfigs parses configuration in format defined by extension
+func parseNodeConfigs(configPath, extension string, rawBytes []byte, strict bool) (*NodeConfigList, error) {
+	var err error
+	list := &NodeConfigList{Source: configPath}
+	switch extension {
+	case ".json":
+		err = parseJSONNodeConfigs(list, rawBytes)
+	case ".yaml", ".yml":
+		err = parseYAMLNodeConfigs(list, rawBytes)
+	default:
+		if isJSON(rawBytes) {
+			err = parseJSONNodeConfigs(list, rawBytes)
+		} else {
+			list.Nodes, list.MalformedLines, err = parseLegacyNodeConfigs(configPath, rawBytes, strict)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Nodes) == 0 {
+		return nil, fmt.Errorf("No nodes found in configuration %s", configPath)
+	}
+
+	return list, nil
+}
+
+// Structured configuration with blockchain settings and list of nodes
+type nodeConfigsFile struct {
+	Blockchains map[string]json.RawMessage `json:"blockchains,omitempty"`
+	Nodes       []json.RawMessage          `json:"nodes"`
+}
+
+// logUnknownFields logs JSON object keys which are not defined by structure tags
+func logUnknownFields(rawObject []byte, v interface{}, source string) error {
+	var fields map[string]interface{}
+	err := json.Unmarshal(rawObject, &fields)
 	if err != nil {
 		return err
 	}
-	nodeConfigsTemp := &[]NodeConfig{}
-	err = json.Unmarshal(rawBytes, nodeConfigsTemp)
+	knownFields := jsonFieldNames(v)
+	for field := range fields {
+		if !knownFields[field] {
+			log.Printf("Unknown field %s at %s, ignoring", field, source)
+		}
+	}
+	return nil
+}
+
+// parseJSONNodeConfigs parses list of nodes or object with blockchains
+// settings and list of nodes
+func parseJSONNodeConfigs(list *NodeConfigList, rawBytes []byte) error {
+	var configsFile nodeConfigsFile
+	var err error
+	if bytes.HasPrefix(bytes.TrimLeft(rawBytes, " \t\r\n"), []byte("{")) {
+		err = logUnknownFields(rawBytes, configsFile, list.Source)
+		if err == nil {
+			err = json.Unmarshal(rawBytes, &configsFile)
+		}
+	} else {
+		err = json.Unmarshal(rawBytes, &configsFile.Nodes)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to parse JSON configuration %s, err: %v", list.Source, err)
+	}
+
+	for blockchain, rawBlockchain := range configsFile.Blockchains {
+		source := fmt.Sprintf("%s.blockchains.%s", list.Source, blockchain)
+		err := logUnknownFields(rawBlockchain, BlockchainConfig{}, source)
+		if err != nil {
+			return fmt.Errorf("Unable to parse blockchain %s, err: %v", source, err)
+		}
+		blockchainConfig := BlockchainConfig{Blockchain: blockchain}
+		err = json.Unmarshal(rawBlockchain, &blockchainConfig)
+		if err != nil {
+			return fmt.Errorf("Unable to parse blockchain %s, err: %v", source, err)
+		}
+		if list.Blockchains == nil {
+			list.Blockchains = make(map[string]BlockchainConfig)
+		}
+		list.Blockchains[blockchain] = blockchainConfig
+	}
+
+	for i, rawNode := range configsFile.Nodes {
+		node, err := parseJSONNodeConfig(rawNode, fmt.Sprintf("%s[%d]", list.Source, i), list.Blockchains)
+		if err != nil {
+			return err
+		}
+		list.Nodes = append(list.Nodes, node)
+	}
+
+	return nil
+}
+
+// parseJSONNodeConfig parses single node, unknown fields are logged and ignored.
+// Not specified settings are inherited from blockchains settings.
+func parseJSONNodeConfig(rawNode []byte, source string, blockchains map[string]BlockchainConfig) (NodeConfig, error) {
+	err := logUnknownFields(rawNode, NodeConfig{}, fmt.Sprintf("node %s", source))
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("Unable to parse node %s, err: %v", source, err)
+	}
+
+	node := NodeConfig{Weight: 1, source: source}
+	err = json.Unmarshal(rawNode, &node)
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("Unable to parse node %s, err: %v", source, err)
+	}
+	// Only omitted ports are inherited from blockchain settings
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(rawNode, &fields)
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("Unable to parse node %s, err: %v", source, err)
+	}
+	for _, field := range []string{"port", "ws_port"} {
+		if value, ok := fields[field]; ok && string(value) == "0" {
+			return NodeConfig{}, fmt.Errorf("Incorrect node %s, err: %s should be greater than zero", source, field)
+		}
+	}
+	node.inherit(blockchainDefaults(blockchains, node.Blockchain))
+	err = node.complete()
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("Incorrect node %s, err: %v", source, err)
+	}
+
+	return node, nil
+}
+
+// YAML configuration converted to JSON to share fields definition and checks
+func parseYAMLNodeConfigs(list *NodeConfigList, rawBytes []byte) error {
+	var rawNodes interface{}
+	err := yaml.Unmarshal(rawBytes, &rawNodes)
+	if err != nil {
+		return fmt.Errorf("Unable to parse YAML configuration %s, err: %v", list.Source, err)
+	}
+	jsonBytes, err := json.Marshal(rawNodes)
+	if err != nil {
+		return fmt.Errorf("Unable to convert YAML configuration %s, err: %v", list.Source, err)
+	}
+
+	return parseJSONNodeConfigs(list, jsonBytes)
+}
+
+// Legacy configuration format with one "blockchain,address[,port[,weight[,ws_port]]]" node
+// per line, IPv6 addresses are enclosed in brackets, like "ethereum,[2001:db8::1],8545".
+// Weight could be left empty to set WebSocket port only, like "ethereum,10.0.0.5,8545,,8546".
+// Port could be omitted for blockchains with default port, like "ethereum,10.0.0.5".
+// Blank lines and lines started with # are skipped, malformed lines are logged
+// and counted or returned as error in strict mode.
+func parseLegacyNodeConfigs(configPath string, rawBytes []byte, strict bool) ([]NodeConfig, int, error) {
+	var nodes []NodeConfig
+	malformedLines := 0
+	for i, line := range strings.Split(string(rawBytes), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		node, err := parseLegacyNodeConfigLine(line)
+		if err != nil {
+			if strict {
+				return nil, 0, fmt.Errorf("Malformed line %d in configuration %s: %s, err: %v", i+1, configPath, line, err)
+			}
+			log.Printf("Skipped malformed line %d in configuration %s: %s, err: %v", i+1, configPath, line, err)
+			malformedLines++
+			continue
+		}
+		node.source = fmt.Sprintf("%s:%d", configPath, i+1)
+		nodes = append(nodes, node)
+	}
+
+	return nodes, malformedLines, nil
+}
+
+func parseLegacyNodeConfigLine(line string) (NodeConfig, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) < 2 || len(fields) > 5 {
+		return NodeConfig{}, fmt.Errorf("expected from 2 to 5 comma separated fields, got %d", len(fields))
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	var port int
+	var err error
+	if len(fields) >= 3 && fields[2] != "" {
+		port, err = strconv.Atoi(fields[2])
+		if err != nil {
+			return NodeConfig{}, fmt.Errorf("unable to parse port %s", fields[2])
+		}
+		// Only omitted port is inherited from blockchain settings
+		if port == 0 {
+			return NodeConfig{}, fmt.Errorf("port should be greater than zero")
+		}
+	}
+	weight := 1
+	if len(fields) >= 4 && fields[3] != "" {
+		weight, err = strconv.Atoi(fields[3])
+		if err != nil {
+			return NodeConfig{}, fmt.Errorf("unable to parse weight %s", fields[3])
+		}
+	}
+	wsPort := 0
+	if len(fields) == 5 {
+		wsPort, err = strconv.Atoi(fields[4])
+		if err != nil {
+			return NodeConfig{}, fmt.Errorf("unable to parse ws port %s", fields[4])
+		}
+		if wsPort == 0 {
+			return NodeConfig{}, fmt.Errorf("ws port should be greater than zero")
+		}
+	}
+	node := NodeConfig{
+		Blockchain: fields[0],
+		Address:    fields[1],
+		Port:       port,
+		WSPort:     wsPort,
+		Weight:     weight,
+	}
+	// Address could be passed with scheme, like https://node1.example.com
+	if strings.Contains(node.Address, "://") {
+		address, err := url.Parse(node.Address)
+		if err != nil {
+			return NodeConfig{}, fmt.Errorf("unable to parse address %s, err: %v", node.Address, err)
+		}
+		if address.Port() != "" || (address.Path != "" && address.Path != "/") {
+			return NodeConfig{}, fmt.Errorf("address %s should not contain port or path", node.Address)
+		}
+		node.Scheme = address.Scheme
+		node.Address = address.Hostname()
+	} else if strings.Contains(node.Address, ":") && !strings.HasPrefix(node.Address, "[") {
+		// Brackets keep line readable and distinguish address from port
+		return NodeConfig{}, fmt.Errorf("IPv6 address %s should be enclosed in brackets", node.Address)
+	}
+	node.inherit(blockchainDefaults(nil, node.Blockchain))
+	err = node.complete()
+	if err != nil {
+		return NodeConfig{}, err
+	}
+
+	return node, nil
+}
+
+// ConfigDuration is time.Duration represented in configuration as string like 5s
+type ConfigDuration time.Duration
+
+func (d ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *ConfigDuration) UnmarshalJSON(data []byte) error {
+	var durationRaw string
+	err := json.Unmarshal(data, &durationRaw)
+	if err != nil {
+		return fmt.Errorf("duration should be a string like 5s, err: %v", err)
+	}
+	duration, err := time.ParseDuration(durationRaw)
 	if err != nil {
 		return err
 	}
-	nodeConfigs = *nodeConfigsTemp
+	*d = ConfigDuration(duration)
 	return nil
 }
 
+// Health check settings, zero values are inherited from blockchain or global settings
+type HealthConfig struct {
+	Interval    ConfigDuration `json:"interval,omitempty"`
+	CallTimeout ConfigDuration `json:"call_timeout,omitempty"`
+	// JSON RPC method returning latest block, like eth_blockNumber
+	Method string `json:"method,omitempty"`
+	// Number of sequential failed checks to mark node as dead
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+	// Number of sequential successful checks to mark dead node as alive
+	HealthyThreshold int `json:"healthy_threshold,omitempty"`
+}
+
+// Method of health check used if not specified in configuration
+const DefaultHealthCheckMethod = "eth_getBlockByNumber"
+
+// DefaultHealthConfig returns global health check settings
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Interval:           ConfigDuration(NB_HEALTH_CHECK_INTERVAL),
+		CallTimeout:        ConfigDuration(NB_HEALTH_CHECK_CALL_TIMEOUT),
+		Method:             DefaultHealthCheckMethod,
+		UnhealthyThreshold: 1,
+		HealthyThreshold:   1,
+	}
+}
+
+// override returns settings with fields replaced by not zero fields of other
+func (hc HealthConfig) override(other *HealthConfig) HealthConfig {
+	if other == nil {
+		return hc
+	}
+	if other.Interval != 0 {
+		hc.Interval = other.Interval
+	}
+	if other.CallTimeout != 0 {
+		hc.CallTimeout = other.CallTimeout
+	}
+	if other.Method != "" {
+		hc.Method = other.Method
+	}
+	if other.UnhealthyThreshold != 0 {
+		hc.UnhealthyThreshold = other.UnhealthyThreshold
+	}
+	if other.HealthyThreshold != 0 {
+		hc.HealthyThreshold = other.HealthyThreshold
+	}
+	return hc
+}
+
+// validate returns problems of effective health check settings
+func (hc HealthConfig) validate() []string {
+	var problems []string
+	if hc.Interval < hc.CallTimeout {
+		problems = append(problems, fmt.Sprintf("health check interval %s shorter than call timeout %s",
+			time.Duration(hc.Interval), time.Duration(hc.CallTimeout)))
+	}
+	if hc.CallTimeout <= 0 {
+		problems = append(problems, "health check call timeout should be positive")
+	}
+	if hc.UnhealthyThreshold < 1 || hc.HealthyThreshold < 1 {
+		problems = append(problems, "health check thresholds should be greater than zero")
+	}
+	return problems
+}
+
+// EffectiveHealthConfig resolves health check settings of node, node settings have
+// precedence over blockchain settings and blockchain settings over global ones
+func (list *NodeConfigList) EffectiveHealthConfig(blockchain string, node *NodeConfig) HealthConfig {
+	health := DefaultHealthConfig()
+	if blockchainConfig, ok := list.Blockchains[blockchain]; ok {
+		health = health.override(blockchainConfig.Health)
+	}
+	if node != nil {
+		health = health.override(node.Health)
+	}
+	return health
+}
+
+// Tag of nodes which serve requests without required tags
+const DefaultNodeTag = "default"
+
+// MatchTags checks node is able to serve request with required tags. Request without
+// tags could be served by untagged nodes or nodes with default tag.
+func MatchTags(nodeTags, requiredTags []string) bool {
+	if len(requiredTags) == 0 {
+		if len(nodeTags) == 0 {
+			return true
+		}
+		requiredTags = []string{DefaultNodeTag}
+	}
+	for _, requiredTag := range requiredTags {
+		found := false
+		for _, nodeTag := range nodeTags {
+			if nodeTag == requiredTag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterByTags returns blockchain nodes able to serve request with required tags
+func (list *NodeConfigList) FilterByTags(blockchain string, tags []string) []NodeConfig {
+	var nodes []NodeConfig
+	for _, node := range list.Nodes {
+		if node.Blockchain == blockchain && MatchTags(node.Tags, tags) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// FilterWebSocket returns blockchain nodes able to serve WebSocket
+// connection with required tags
+func (list *NodeConfigList) FilterWebSocket(blockchain string, tags []string) []NodeConfig {
+	var nodes []NodeConfig
+	for _, node := range list.FilterByTags(blockchain, tags) {
+		if node.HasWebSocket() {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Weights returns weights of blockchain nodes by endpoint
+func (list *NodeConfigList) Weights(blockchain string) map[string]int {
+	weights := make(map[string]int)
+	for _, node := range list.Nodes {
+		if node.Blockchain == blockchain {
+			weights[node.Endpoint] = node.Weight
+		}
+	}
+	return weights
+}
+
+// ConfigErrors aggregates all problems found in configuration
+type ConfigErrors []string
+
+func (e ConfigErrors) Error() string {
+	return fmt.Sprintf("%d configuration errors: %s", len(e), strings.Join(e, "; "))
+}
+
+// Filter returns errors of environment variables with provided names,
+// messages of LoadConfig are started with variable name
+func (e ConfigErrors) Filter(names ...string) ConfigErrors {
+	var errs ConfigErrors
+	for _, message := range e {
+		for _, name := range names {
+			if strings.HasPrefix(message, name+" ") || strings.HasPrefix(message, name+",") {
+				errs = append(errs, message)
+				break
+			}
+		}
+	}
+	return errs
+}
+
+var hostnameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+var numericHostnameRe = regexp.MustCompile(`^[0-9.]+$`)
+
+// validAddress checks address is an IP or looks like resolvable hostname
+func validAddress(address string) bool {
+	if net.ParseIP(address) != nil {
+		return true
+	}
+	// Numeric addresses which are not parsed as IP, like 10.0.0.300
+	if numericHostnameRe.MatchString(address) {
+		return false
+	}
+	return len(address) <= 253 && hostnameRe.MatchString(address)
+}
+
+// knownBlockchains returns set of blockchain names from NB_KNOWN_BLOCKCHAINS
+func knownBlockchains() map[string]bool {
+	blockchains := make(map[string]bool)
+	for _, b := range appConfig.KnownBlockchains {
+		blockchains[b] = true
+	}
+	return blockchains
+}
+
+// Validate checks list of nodes for duplicates, incorrect addresses and ports.
+// Unknown blockchain names returned as warnings or as errors in strict mode.
+func (list *NodeConfigList) Validate(strict bool) ([]string, error) {
+	var warnings []string
+	var errs ConfigErrors
+
+	known := knownBlockchains()
+	nodesSet := make(map[string]string)
+	for _, node := range list.Nodes {
+		if !known[node.Blockchain] {
+			problem := fmt.Sprintf("%s: unknown blockchain %s", node.source, node.Blockchain)
+			if strict {
+				errs = append(errs, problem)
+			} else {
+				warnings = append(warnings, problem)
+			}
+		}
+		if !validAddress(node.Address) {
+			errs = append(errs, fmt.Sprintf("%s: incorrect address %s", node.source, node.Address))
+		}
+		if node.Port < 0 || node.Port > 65535 {
+			errs = append(errs, fmt.Sprintf("%s: port %d out of range", node.source, node.Port))
+		}
+		if !nodeSchemes[node.Scheme] {
+			errs = append(errs, fmt.Sprintf("%s: unsupported scheme %s", node.source, node.Scheme))
+		}
+		if node.WSPort != 0 {
+			if node.WSPort < 0 || node.WSPort > 65535 {
+				errs = append(errs, fmt.Sprintf("%s: ws port %d out of range", node.source, node.WSPort))
+			}
+			if node.WSPort == node.Port {
+				errs = append(errs, fmt.Sprintf("%s: ws port %d should differ from port", node.source, node.WSPort))
+			}
+			if node.WSScheme != "ws" && node.WSScheme != "wss" {
+				errs = append(errs, fmt.Sprintf("%s: unsupported ws scheme %s", node.source, node.WSScheme))
+			}
+			if node.Scheme == "ws" || node.Scheme == "wss" {
+				errs = append(errs, fmt.Sprintf("%s: ws port set for node with %s scheme", node.source, node.Scheme))
+			}
+		}
+		if node.InsecureSkipVerify || node.CABundle != "" {
+			if node.Scheme != "https" && node.Scheme != "wss" {
+				warnings = append(warnings, fmt.Sprintf("%s: TLS options ignored for %s scheme", node.source, node.Scheme))
+			}
+			if _, err := node.TLSConfig(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", node.source, err))
+			}
+		}
+		if node.InsecureSkipVerify {
+			warnings = append(warnings, fmt.Sprintf("%s: TLS certificate verification disabled for %s, it is insecure", node.source, node.Endpoint))
+		}
+		if node.Weight < 1 {
+			errs = append(errs, fmt.Sprintf("%s: weight %d should be greater than zero", node.source, node.Weight))
+		}
+
+		nodeKey := fmt.Sprintf("%s,%s,%d", node.Blockchain, node.Address, node.effectivePort())
+		if source, ok := nodesSet[nodeKey]; ok {
+			errs = append(errs, fmt.Sprintf("%s: duplicate of node at %s", node.source, source))
+		} else {
+			nodesSet[nodeKey] = node.source
+		}
+	}
+
+	// Requests without tags should be served for each blockchain
+	blockchains := make(map[string]bool)
+	for _, node := range list.Nodes {
+		blockchains[node.Blockchain] = true
+	}
+
+	for i := range list.Nodes {
+		node := &list.Nodes[i]
+		if node.Health == nil {
+			continue
+		}
+		for _, problem := range list.EffectiveHealthConfig(node.Blockchain, node).validate() {
+			errs = append(errs, fmt.Sprintf("%s: %s", node.source, problem))
+		}
+	}
+	for b := range list.Blockchains {
+		if !blockchains[b] {
+			warnings = append(warnings, fmt.Sprintf("%s: settings of %s blockchain without nodes", list.Source, b))
+		}
+		for _, problem := range list.EffectiveHealthConfig(b, nil).validate() {
+			errs = append(errs, fmt.Sprintf("%s: %s blockchain %s", list.Source, b, problem))
+		}
+	}
+	for b := range blockchains {
+		if len(list.FilterByTags(b, nil)) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: no untagged or %s tagged nodes for %s blockchain", list.Source, DefaultNodeTag, b))
+		}
+	}
+
+	if len(errs) > 0 {
+		return warnings, errs
+	}
+	return warnings, nil
+}
+
+// Summary describes loaded nodes and source of configuration
+func (list *NodeConfigList) Summary() string {
+	var blockchains []string
+	blockchainsSet := make(map[string]bool)
+	for _, node := range list.Nodes {
+		if !blockchainsSet[node.Blockchain] {
+			blockchainsSet[node.Blockchain] = true
+			blockchains = append(blockchains, node.Blockchain)
+		}
+	}
+
+	summary := fmt.Sprintf("%d nodes of %s blockchains from %s", len(list.Nodes), strings.Join(blockchains, ", "), list.Source)
+	if list.MalformedLines > 0 {
+		summary += fmt.Sprintf(", %d malformed lines skipped", list.MalformedLines)
+	}
+	return summary
+}
+
+// LoadNodeConfigList parses and validates nodes configuration from source
+// set with MOONSTREAM_NODES_SOURCE, list is not published until it is passed
+// to SetNodeConfigList.
+func LoadNodeConfigList(configPath string, strict bool) (*NodeConfigList, error) {
+	list, err := parseNodeConfigSource(configPath, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNodeConfigList(list, strict)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// parseNodeConfigSource parses nodes configuration from source set with
+// MOONSTREAM_NODES_SOURCE without validation
+func parseNodeConfigSource(configPath string, strict bool) (*NodeConfigList, error) {
+	switch appConfig.NodesSource {
+	case "", NodesSourceFile:
+		return loadFileNodeConfigList(configPath, strict)
+	case NodesSourceBugout:
+		return loadBugoutNodeConfigList(appConfig.NodesJournalID)
+	default:
+		return nil, fmt.Errorf("Unsupported MOONSTREAM_NODES_SOURCE %s, expected %s or %s", appConfig.NodesSource, NodesSourceFile, NodesSourceBugout)
+	}
+}
+
+// checkNodeConfigList validates list and logs warnings and summary
+func checkNodeConfigList(list *NodeConfigList, strict bool) error {
+	warnings, err := list.Validate(strict)
+	for _, warning := range warnings {
+		log.Printf("Warning at nodes configuration %s", warning)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %s", list.Summary())
+
+	return nil
+}
+
+// loadFileNodeConfigList parses configuration file or MOONSTREAM_NODES, file has
+// precedence and MOONSTREAM_NODES is used if file path is empty or file not found.
+func loadFileNodeConfigList(configPath string, strict bool) (*NodeConfigList, error) {
+	var list *NodeConfigList
+	var err error
+	configExists := false
+	if configPath != "" {
+		configExists, err = CheckPathExists(configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case configExists:
+		if appConfig.Nodes != "" {
+			log.Printf("Warning, both configuration file %s and %s are set, file is used", configPath, NodesEnvSource)
+		}
+		list, err = ParseNodeConfigs(configPath, strict)
+	case appConfig.Nodes != "":
+		list, err = ParseNodeConfigsEnv(appConfig.Nodes, strict)
+	case configPath == "":
+		err = fmt.Errorf("Configuration file or %s should be specified", NodesEnvSource)
+	default:
+		err = fmt.Errorf("Configuration file %s not found", configPath)
+	}
+
+	return list, err
+}
+
+// GetNodeConfigList returns current list of nodes, it should not be modified
+func GetNodeConfigList() *NodeConfigList {
+	nodeConfigListMux.RLock()
+	defer nodeConfigListMux.RUnlock()
+
+	return nodeConfigList
+}
+
+// SetNodeConfigList replaces current list of nodes
+func SetNodeConfigList(list *NodeConfigList) {
+	nodeConfigListMux.Lock()
+	nodeConfigList = list
+	nodeConfigListMux.Unlock()
+}
+
 type ConfigPlacement struct {
 	ConfigDirPath   string
 	ConfigDirExists bool
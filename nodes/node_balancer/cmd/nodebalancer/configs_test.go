@@ -0,0 +1,966 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withoutSources clears sources of nodes to compare nodes from different files
+func withoutSources(nodes []NodeConfig) []NodeConfig {
+	var result []NodeConfig
+	for _, node := range nodes {
+		node.source = ""
+		result = append(result, node)
+	}
+	return result
+}
+
+// writeConfig writes configuration content to temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	configPath := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return configPath
+}
+
+func TestParseNodeConfigs(t *testing.T) {
+	expected := []NodeConfig{
+		{Blockchain: "ethereum", Endpoint: "http://10.0.0.5:8545", Address: "10.0.0.5", Port: 8545, Scheme: "http", Weight: 1},
+		{Blockchain: "ethereum", Endpoint: "http://10.0.0.6:8545", Address: "10.0.0.6", Port: 8545, Scheme: "http", Weight: 1},
+		{Blockchain: "polygon", Endpoint: "http://10.0.1.5:8545", Address: "10.0.1.5", Port: 8545, Scheme: "http", Weight: 3},
+	}
+
+	var cases = []struct {
+		configPath string
+	}{
+		{"testdata/nodes.json"},
+		{"testdata/nodes.yaml"},
+		{"testdata/nodes.txt"},
+		// JSON configuration generated by previous versions at config.txt
+		{"testdata/config.txt"},
+	}
+	for _, c := range cases {
+		list, err := ParseNodeConfigs(c.configPath, true)
+		if err != nil {
+			t.Fatalf("Unable to parse %s, err: %v", c.configPath, err)
+		}
+		if !reflect.DeepEqual(withoutSources(list.Nodes), expected) {
+			t.Fatalf("Wrong nodes parsed from %s: %+v", c.configPath, list.Nodes)
+		}
+	}
+}
+
+func TestParseNodeConfigsErrors(t *testing.T) {
+	var cases = []struct {
+		name    string
+		content string
+		strict  bool
+	}{
+		{"empty.txt", "", false},
+		{"comments.txt", "# ethereum,10.0.0.5,8545\n\n", false},
+		{"object.txt", `{"blockchain": "ethereum", "endpoint": "http://127.0.0.1:8545"}`, false},
+		{"truncated.txt", "ethereum,10.0.0.5,8545\nsolana,10.0.0.6", true},
+		{"port.txt", "ethereum,10.0.0.5,port", false},
+		{"empty.json", "[]", false},
+		{"truncated.json", `[{"blockchain": "ethereum", "endpoint": "http://127.0.0.1:8545"`, false},
+		{"no_endpoint.json", `[{"blockchain": "ethereum"}]`, false},
+		{"no_blockchain.yaml", "- endpoint: http://127.0.0.1:8545", false},
+	}
+	for _, c := range cases {
+		configPath := writeConfig(t, c.name, c.content)
+		if _, err := ParseNodeConfigs(configPath, c.strict); err == nil {
+			t.Fatalf("Expected error for configuration %s", c.name)
+		}
+	}
+}
+
+func TestParseLegacyNodeConfigs(t *testing.T) {
+	expected := []NodeConfig{
+		{Blockchain: "ethereum", Endpoint: "http://10.0.0.5:8545", Address: "10.0.0.5", Port: 8545, Scheme: "http", Weight: 1, source: "testdata/nodes_comments.txt:3"},
+		{Blockchain: "ethereum", Endpoint: "http://10.0.0.6:8545", Address: "10.0.0.6", Port: 8545, Scheme: "http", Weight: 1, source: "testdata/nodes_comments.txt:4"},
+		{Blockchain: "polygon", Endpoint: "http://10.0.1.5:8545", Address: "10.0.1.5", Port: 8545, Scheme: "http", Weight: 1, source: "testdata/nodes_comments.txt:9"},
+	}
+
+	list, err := ParseNodeConfigs("testdata/nodes_comments.txt", false)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	if !reflect.DeepEqual(list.Nodes, expected) {
+		t.Fatalf("Wrong nodes parsed: %+v", list.Nodes)
+	}
+	if list.MalformedLines != 2 {
+		t.Fatalf("Expected 2 malformed lines, got %d", list.MalformedLines)
+	}
+
+	_, err = ParseNodeConfigs("testdata/nodes_comments.txt", true)
+	if err == nil || !strings.Contains(err.Error(), "line 7") {
+		t.Fatalf("Expected error for malformed line 7 in strict mode, got %v", err)
+	}
+}
+
+func TestValidateNodeConfigs(t *testing.T) {
+	var cases = []struct {
+		content  string
+		strict   bool
+		warnings int
+		errors   int
+	}{
+		{"ethereum,10.0.0.5,8545\nethereum,node1.example.com,8545", false, 0, 0},
+		{"ethereum,10.0.0.5,8545\nethereum,10.0.0.5,8545\nethereum,10.0.0.5,8546", false, 0, 1},
+		{"ethereum,10.0.0.300,8545\nethereum,bad_host!,8545\nethereum,-node.example.com,8545", false, 0, 3},
+		{"ethereum,10.0.0.5,70000", false, 0, 1},
+		{"etherium,10.0.0.5,8545", false, 1, 0},
+		{"etherium,10.0.0.5,8545", true, 0, 1},
+		{"etherium,10.0.0.5,8545\netherium,10.0.0.5,8545", true, 0, 3},
+		{"ethereum,10.0.0.5,8545,0\nethereum,10.0.0.6,8545,-1", false, 0, 2},
+		{"ethereum,[2001:db8::1],8545\nethereum,[2001:db8:0:0::1],8545", false, 0, 1},
+		{"ethereum,[2001:db8::zz],8545", false, 0, 1},
+	}
+	for i, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, "nodes.txt", c.content), true)
+		if err != nil {
+			t.Fatalf("Unable to parse case %d, err: %v", i, err)
+		}
+
+		warnings, err := list.Validate(c.strict)
+		if len(warnings) != c.warnings {
+			t.Fatalf("Case %d: expected %d warnings, got %v", i, c.warnings, warnings)
+		}
+		if c.errors == 0 {
+			if err != nil {
+				t.Fatalf("Case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		errs, ok := err.(ConfigErrors)
+		if !ok || len(errs) != c.errors {
+			t.Fatalf("Case %d: expected %d errors, got %v", i, c.errors, err)
+		}
+	}
+}
+
+func TestValidateNodeConfigsEndpoints(t *testing.T) {
+	var cases = []struct {
+		content string
+		parsed  bool
+		errors  int
+	}{
+		{`[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5"}, {"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8080"}]`, true, 0},
+		// Implicit port of scheme is the same as explicit one
+		{`[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5"}, {"blockchain": "ethereum", "endpoint": "http://10.0.0.5:80"}]`, true, 1},
+		{`[{"blockchain": "ethereum", "endpoint": "https://node1.example.com:443"}, {"blockchain": "ethereum", "endpoint": "https://node1.example.com"}]`, true, 1},
+		{`[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:0"}]`, false, 0},
+	}
+	for i, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, "nodes.json", c.content), true)
+		if !c.parsed {
+			if err == nil {
+				t.Fatalf("Case %d: expected parsing error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unable to parse case %d, err: %v", i, err)
+		}
+
+		_, err = list.Validate(false)
+		if c.errors == 0 {
+			if err != nil {
+				t.Fatalf("Case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		errs, ok := err.(ConfigErrors)
+		if !ok || len(errs) != c.errors {
+			t.Fatalf("Case %d: expected %d errors, got %v", i, c.errors, err)
+		}
+	}
+}
+
+func TestValidateNodeConfigsKnownBlockchains(t *testing.T) {
+	defer func(config Config) { appConfig = config }(appConfig)
+	appConfig.KnownBlockchains = []string{"ethereum", "solana"}
+
+	list := &NodeConfigList{Nodes: []NodeConfig{
+		{Blockchain: "solana", Address: "10.0.0.5", Port: 8899, source: "nodes.txt:1"},
+		{Blockchain: "polygon", Address: "10.0.0.6", Port: 8545, Scheme: "http", Weight: 1, source: "nodes.txt:2"},
+	}}
+	_, err := list.Validate(true)
+	if err == nil || !strings.Contains(err.Error(), "nodes.txt:2: unknown blockchain polygon") {
+		t.Fatalf("Expected unknown polygon blockchain error, got %v", err)
+	}
+}
+
+func TestNodeConfigsWeights(t *testing.T) {
+	var cases = []struct {
+		name     string
+		content  string
+		expected map[string]int
+		errors   int
+	}{
+		{"default.txt", "ethereum,10.0.0.5,8545\nethereum,10.0.0.6,8545", map[string]int{"http://10.0.0.5:8545": 1, "http://10.0.0.6:8545": 1}, 0},
+		{"explicit.txt", "ethereum,10.0.0.5,8545,4\nethereum,10.0.0.6,8545", map[string]int{"http://10.0.0.5:8545": 4, "http://10.0.0.6:8545": 1}, 0},
+		{"default.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545"}]`, map[string]int{"http://10.0.0.5:8545": 1}, 0},
+		{"explicit.yaml", "- {blockchain: ethereum, endpoint: 'http://10.0.0.5:8545', weight: 2}", map[string]int{"http://10.0.0.5:8545": 2}, 0},
+		{"zero.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "weight": 0}]`, nil, 1},
+		{"negative.yaml", "- {blockchain: ethereum, endpoint: 'http://10.0.0.5:8545', weight: -2}", nil, 1},
+	}
+	for _, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err != nil {
+			t.Fatalf("Unable to parse %s, err: %v", c.name, err)
+		}
+		_, err = list.Validate(false)
+		if c.errors > 0 {
+			if errs, ok := err.(ConfigErrors); !ok || len(errs) != c.errors {
+				t.Fatalf("Expected %d errors for %s, got %v", c.errors, c.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", c.name, err)
+		}
+		if weights := list.Weights("ethereum"); !reflect.DeepEqual(weights, c.expected) {
+			t.Fatalf("Wrong weights for %s: %v", c.name, weights)
+		}
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	list, err := LoadNodeConfigList("testdata/nodes_tags.yaml", true)
+	if err != nil {
+		t.Fatalf("Unable to load configuration, err: %v", err)
+	}
+
+	var cases = []struct {
+		blockchain string
+		tags       []string
+		expected   []string
+	}{
+		{"ethereum", nil, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"}},
+		{"ethereum", []string{"archive"}, []string{"http://10.0.0.6:8545", "http://10.0.0.7:8545"}},
+		{"ethereum", []string{"archive", "tracing"}, []string{"http://10.0.0.6:8545"}},
+		{"ethereum", []string{"light"}, nil},
+		{"polygon", []string{"archive"}, nil},
+		{"polygon", nil, []string{"http://10.0.1.5:8545"}},
+	}
+	for _, c := range cases {
+		var endpoints []string
+		for _, node := range list.FilterByTags(c.blockchain, c.tags) {
+			endpoints = append(endpoints, node.Endpoint)
+		}
+		if !reflect.DeepEqual(endpoints, c.expected) {
+			t.Fatalf("Wrong %s nodes for tags %v: %v", c.blockchain, c.tags, endpoints)
+		}
+	}
+}
+
+func TestValidateNodeConfigsTags(t *testing.T) {
+	configPath := writeConfig(t, "nodes.json", `[
+		{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545"},
+		{"blockchain": "polygon", "endpoint": "http://10.0.1.5:8545", "tags": ["archive"]}
+	]`)
+	_, err := LoadNodeConfigList(configPath, false)
+	if err == nil || !strings.Contains(err.Error(), "for polygon blockchain") {
+		t.Fatalf("Expected error for polygon without default nodes, got %v", err)
+	}
+}
+
+func TestNodeConfigsSchemes(t *testing.T) {
+	var cases = []struct {
+		name     string
+		content  string
+		scheme   string
+		endpoint string
+		errors   int
+	}{
+		{"default.txt", "ethereum,node1.example.com,8545", "http", "http://node1.example.com:8545", 0},
+		{"https.txt", "ethereum,https://node1.example.com,8545", "https", "https://node1.example.com:8545", 0},
+		{"https.json", `[{"blockchain": "ethereum", "endpoint": "https://node1.example.com:8545"}]`, "https", "https://node1.example.com:8545", 0},
+		{"path.json", `[{"blockchain": "ethereum", "endpoint": "https://provider.example.com/v1/key"}]`, "https", "https://provider.example.com/v1/key", 0},
+		{"wss.yaml", "- {blockchain: ethereum, address: node1.example.com, port: 8546, scheme: wss}", "wss", "wss://node1.example.com:8546", 0},
+		{"ftp.json", `[{"blockchain": "ethereum", "endpoint": "ftp://node1.example.com:8545"}]`, "ftp", "ftp://node1.example.com:8545", 1},
+		{"ca.json", `[{"blockchain": "ethereum", "endpoint": "https://node1.example.com", "ca_bundle": "testdata/missing.pem"}]`, "https", "https://node1.example.com", 1},
+	}
+	for _, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err != nil {
+			t.Fatalf("Unable to parse %s, err: %v", c.name, err)
+		}
+		if list.Nodes[0].Scheme != c.scheme || list.Nodes[0].URL() != c.endpoint {
+			t.Fatalf("Wrong scheme or URL for %s: %s %s", c.name, list.Nodes[0].Scheme, list.Nodes[0].URL())
+		}
+		_, err = list.Validate(false)
+		if errs, _ := err.(ConfigErrors); len(errs) != c.errors {
+			t.Fatalf("Expected %d errors for %s, got %v", c.errors, c.name, err)
+		}
+	}
+
+	conflictPath := writeConfig(t, "conflict.json", `[{"blockchain": "ethereum", "endpoint": "https://node1.example.com", "scheme": "http"}]`)
+	if _, err := ParseNodeConfigs(conflictPath, true); err == nil {
+		t.Fatal("Expected error for scheme differs from endpoint")
+	}
+}
+
+func TestNodeConfigsInsecureSkipVerify(t *testing.T) {
+	list, err := ParseNodeConfigs(writeConfig(t, "insecure.json", `[{"blockchain": "ethereum", "endpoint": "https://node1.example.com", "insecure_skip_verify": true}]`), true)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	warnings, err := list.Validate(false)
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "insecure") {
+		t.Fatalf("Expected insecure warning, got %v %v", warnings, err)
+	}
+}
+
+func TestNodeConfigsAddresses(t *testing.T) {
+	var cases = []struct {
+		name     string
+		content  string
+		address  string
+		endpoint string
+	}{
+		{"ipv4.txt", "ethereum,10.0.0.5,8545", "10.0.0.5", "http://10.0.0.5:8545"},
+		{"hostname.txt", "ethereum,node1.example.com,8545", "node1.example.com", "http://node1.example.com:8545"},
+		{"ipv6.txt", "ethereum,[2001:db8::1],8545", "2001:db8::1", "http://[2001:db8::1]:8545"},
+		{"ipv6_scheme.txt", "ethereum,https://[::1],8545", "::1", "https://[::1]:8545"},
+		{"ipv6_endpoint.json", `[{"blockchain": "ethereum", "endpoint": "http://[2001:db8::1]:8545"}]`, "2001:db8::1", "http://[2001:db8::1]:8545"},
+		{"ipv6_address.json", `[{"blockchain": "ethereum", "address": "2001:db8:0:0::1", "port": 8545}]`, "2001:db8::1", "http://[2001:db8::1]:8545"},
+		{"ipv6_brackets.yaml", `- {blockchain: ethereum, address: "[2001:db8::1]", port: 8545}`, "2001:db8::1", "http://[2001:db8::1]:8545"},
+	}
+	for _, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err != nil {
+			t.Fatalf("Unable to parse %s, err: %v", c.name, err)
+		}
+		if list.Nodes[0].Address != c.address || list.Nodes[0].URL() != c.endpoint {
+			t.Fatalf("Wrong address or URL for %s: %s %s", c.name, list.Nodes[0].Address, list.Nodes[0].URL())
+		}
+		if _, err := list.Validate(false); err != nil {
+			t.Fatalf("Unexpected validation error for %s: %v", c.name, err)
+		}
+	}
+
+	for _, line := range []string{"ethereum,2001:db8::1,8545", "ethereum,https://[::1]:8545,8545"} {
+		if _, err := ParseNodeConfigs(writeConfig(t, "nodes.txt", line), true); err == nil {
+			t.Fatalf("Expected error for line %s", line)
+		}
+	}
+}
+
+func TestNodeConfigsWebSocket(t *testing.T) {
+	var cases = []struct {
+		configPath string
+		expected   []string
+	}{
+		{"testdata/nodes_ws.yaml", []string{"ws://10.0.0.5:8546", "wss://node1.example.com:8546", "", "wss://10.0.1.5:8546"}},
+		{"testdata/nodes_ws.txt", []string{"ws://10.0.0.5:8546", "wss://node1.example.com:8546", "", ""}},
+	}
+	for _, c := range cases {
+		list, err := LoadNodeConfigList(c.configPath, true)
+		if err != nil {
+			t.Fatalf("Unable to load %s, err: %v", c.configPath, err)
+		}
+		var wsEndpoints []string
+		for _, node := range list.Nodes {
+			wsEndpoints = append(wsEndpoints, node.WSURL())
+		}
+		if !reflect.DeepEqual(wsEndpoints, c.expected) {
+			t.Fatalf("Wrong WebSocket endpoints parsed from %s: %v", c.configPath, wsEndpoints)
+		}
+		// HTTP endpoints are not changed by WebSocket ports
+		if list.Nodes[0].URL() != "http://10.0.0.5:8545" || list.Nodes[1].URL() != "https://node1.example.com:8545" {
+			t.Fatalf("Wrong endpoints parsed from %s: %s %s", c.configPath, list.Nodes[0].URL(), list.Nodes[1].URL())
+		}
+
+		var endpoints []string
+		for _, node := range list.FilterWebSocket("ethereum", nil) {
+			endpoints = append(endpoints, node.Endpoint)
+		}
+		if !reflect.DeepEqual(endpoints, []string{"http://10.0.0.5:8545", "https://node1.example.com:8545"}) {
+			t.Fatalf("Wrong WebSocket nodes from %s: %v", c.configPath, endpoints)
+		}
+	}
+
+	var errorCases = []struct {
+		name    string
+		content string
+	}{
+		{"same_port.txt", "ethereum,10.0.0.5,8545,1,8545"},
+		{"range.txt", "ethereum,10.0.0.5,8545,1,70000"},
+		{"ws_scheme.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "ws_port": 8546, "ws_scheme": "https"}]`},
+		{"ws_node.yaml", "- {blockchain: ethereum, endpoint: ws://10.0.0.5:8546, ws_port: 8547}"},
+	}
+	for _, c := range errorCases {
+		list, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err != nil {
+			t.Fatalf("Unable to parse %s, err: %v", c.name, err)
+		}
+		if _, err := list.Validate(false); err == nil {
+			t.Fatalf("Expected validation error for %s", c.name)
+		}
+	}
+	for _, content := range []string{"ethereum,10.0.0.5,8545,1,ws", "ethereum,10.0.0.5,8545,1,8546,8547"} {
+		if _, err := ParseNodeConfigs(writeConfig(t, "nodes.txt", content), true); err == nil {
+			t.Fatalf("Expected error for line %s", content)
+		}
+	}
+	schemePath := writeConfig(t, "scheme.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "ws_scheme": "wss"}]`)
+	if _, err := ParseNodeConfigs(schemePath, true); err == nil {
+		t.Fatal("Expected error for ws_scheme without ws_port")
+	}
+}
+
+func TestNodeConfigsBlockchainDefaults(t *testing.T) {
+	list, err := ParseNodeConfigs("testdata/nodes_defaults.yaml", true)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	var cases = []struct {
+		endpoint   string
+		wsEndpoint string
+	}{
+		// Port and WebSocket port inherited from blockchain settings
+		{"http://10.0.0.5:8645", "ws://10.0.0.5:8646"},
+		// Node settings override blockchain settings
+		{"http://10.0.0.6:8545", "ws://10.0.0.6:8546"},
+		{"http://10.0.0.7:9545", "ws://10.0.0.7:8646"},
+		{"https://node1.example.com:443", ""},
+		{"http://10.0.1.6:8545", ""},
+		{"http://10.0.2.5:8899", ""},
+		// Built-in default port
+		{"http://10.0.3.5:8545", ""},
+	}
+	if len(list.Nodes) != len(cases) {
+		t.Fatalf("Expected %d nodes, got %d", len(cases), len(list.Nodes))
+	}
+	for i, c := range cases {
+		if list.Nodes[i].URL() != c.endpoint || list.Nodes[i].WSURL() != c.wsEndpoint {
+			t.Fatalf("Node %d: expected %s and %s, got %s and %s", i, c.endpoint, c.wsEndpoint, list.Nodes[i].URL(), list.Nodes[i].WSURL())
+		}
+	}
+
+	var legacyCases = []struct {
+		line     string
+		endpoint string
+	}{
+		{"ethereum,10.0.0.5", "http://10.0.0.5:8545"},
+		{"ethereum,https://node1.example.com", "https://node1.example.com:8545"},
+		{"polygon,10.0.1.5,,2", "http://10.0.1.5:8545"},
+		{"ethereum,10.0.0.5,9545", "http://10.0.0.5:9545"},
+	}
+	for _, c := range legacyCases {
+		list, err := ParseNodeConfigs(writeConfig(t, "nodes.txt", c.line), true)
+		if err != nil {
+			t.Fatalf("Unable to parse line %s, err: %v", c.line, err)
+		}
+		if list.Nodes[0].URL() != c.endpoint {
+			t.Fatalf("Expected %s for line %s, got %s", c.endpoint, c.line, list.Nodes[0].URL())
+		}
+	}
+
+	var errorCases = []struct {
+		name    string
+		content string
+	}{
+		{"nodes.txt", "solana,10.0.2.5"},
+		{"nodes.json", `[{"blockchain": "solana", "address": "10.0.2.5"}]`},
+		{"nodes.yaml", "blockchains:\n  solana:\n    scheme: https\nnodes:\n  - {blockchain: solana, address: 10.0.2.5}"},
+	}
+	for _, c := range errorCases {
+		_, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err == nil || !strings.Contains(err.Error(), "solana blockchain has no default port") {
+			t.Fatalf("Expected missing default port error for %s, got %v", c.name, err)
+		}
+	}
+
+	// Explicit zero ports are not replaced by blockchain settings
+	var zeroPortCases = []struct {
+		name    string
+		content string
+	}{
+		{"nodes.txt", "ethereum,10.0.0.5,0"},
+		{"nodes.txt", "ethereum,10.0.0.5,8545,,0"},
+		{"nodes.json", `[{"blockchain": "ethereum", "address": "10.0.0.5", "port": 0}]`},
+		{"nodes.json", `[{"blockchain": "ethereum", "address": "10.0.0.5", "port": 8545, "ws_port": 0}]`},
+		{"nodes.yaml", "- {blockchain: ethereum, address: 10.0.0.5, port: 0}"},
+	}
+	for _, c := range zeroPortCases {
+		_, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if err == nil || !strings.Contains(err.Error(), "should be greater than zero") {
+			t.Fatalf("Expected zero port error for %s, got %v", c.content, err)
+		}
+	}
+}
+
+func TestLoadNodeConfigListEnv(t *testing.T) {
+	defer func(config Config) { appConfig = config }(appConfig)
+
+	fileNodes, err := ParseNodeConfigs("testdata/nodes.json", true)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	envNodes := []NodeConfig{
+		{Blockchain: "ethereum", Endpoint: "http://10.0.0.7:8545", Address: "10.0.0.7", Port: 8545, Scheme: "http", Weight: 1},
+	}
+	missingPath := filepath.Join(t.TempDir(), "config.txt")
+
+	var cases = []struct {
+		configPath string
+		env        string
+		source     string
+		expected   []NodeConfig
+	}{
+		{"", "ethereum,10.0.0.5,8545;ethereum,10.0.0.6,8545; polygon,10.0.1.5,8545,3", NodesEnvSource, fileNodes.Nodes},
+		{missingPath, `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.7:8545"}]`, NodesEnvSource, envNodes},
+		{missingPath, "ethereum,10.0.0.7,8545;", NodesEnvSource, envNodes},
+		// File has precedence over environment variable
+		{"testdata/nodes.json", "ethereum,10.0.0.7,8545", "testdata/nodes.json", fileNodes.Nodes},
+	}
+	for i, c := range cases {
+		appConfig.Nodes = c.env
+		list, err := LoadNodeConfigList(c.configPath, true)
+		if err != nil {
+			t.Fatalf("Case %d: unable to load configuration, err: %v", i, err)
+		}
+		if list.Source != c.source || !strings.HasSuffix(list.Summary(), "from "+c.source) {
+			t.Fatalf("Case %d: expected source %s, got %s", i, c.source, list.Summary())
+		}
+		if !reflect.DeepEqual(withoutSources(list.Nodes), withoutSources(c.expected)) {
+			t.Fatalf("Case %d: wrong nodes loaded: %+v", i, list.Nodes)
+		}
+	}
+
+	var errorCases = []struct {
+		configPath string
+		env        string
+	}{
+		{"", ""},
+		{missingPath, ""},
+		{missingPath, "solana,10.0.0.7"},
+		{missingPath, "ethereum,10.0.0.7,8545;ethereum,10.0.0.7,8545"},
+		{missingPath, `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.7:8545"`},
+	}
+	for i, c := range errorCases {
+		appConfig.Nodes = c.env
+		if _, err := LoadNodeConfigList(c.configPath, true); err == nil {
+			t.Fatalf("Case %d: expected error for %s configuration", i, c.env)
+		}
+	}
+
+	appConfig.Nodes = "ethereum,10.0.0.7,8545;solana,10.0.0.8"
+	list, err := LoadNodeConfigList("", false)
+	if err != nil || list.MalformedLines != 1 || list.Nodes[0].source != "MOONSTREAM_NODES:1" {
+		t.Fatalf("Expected malformed line skipped, got %+v %v", list, err)
+	}
+}
+
+func TestEffectiveHealthConfig(t *testing.T) {
+	list, err := ParseNodeConfigs("testdata/nodes_health.yaml", true)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	if _, err := list.Validate(true); err != nil {
+		t.Fatalf("Unexpected validation error %v", err)
+	}
+
+	var cases = []struct {
+		blockchain string
+		node       *NodeConfig
+		expected   HealthConfig
+	}{
+		// Global settings
+		{"ethereum", &list.Nodes[0], DefaultHealthConfig()},
+		{"xdai", nil, DefaultHealthConfig()},
+		// Blockchain settings override global ones
+		{"polygon", &list.Nodes[1], HealthConfig{
+			Interval: ConfigDuration(10 * time.Second), CallTimeout: ConfigDuration(4 * time.Second),
+			Method: DefaultHealthCheckMethod, UnhealthyThreshold: 3, HealthyThreshold: 1,
+		}},
+		// Node settings override blockchain ones
+		{"polygon", &list.Nodes[2], HealthConfig{
+			Interval: ConfigDuration(10 * time.Second), CallTimeout: ConfigDuration(8 * time.Second),
+			Method: "eth_blockNumber", UnhealthyThreshold: 3, HealthyThreshold: 1,
+		}},
+	}
+	for i, c := range cases {
+		health := list.EffectiveHealthConfig(c.blockchain, c.node)
+		if health != c.expected {
+			t.Fatalf("Case %d: expected %+v, got %+v", i, c.expected, health)
+		}
+	}
+}
+
+func TestValidateHealthConfig(t *testing.T) {
+	var cases = []struct {
+		name    string
+		content string
+		errors  int
+		parsed  bool
+	}{
+		{"blockchain.json", `{"blockchains": {"ethereum": {"health": {"interval": "1s"}}}, "nodes": [{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545"}]}`, 1, true},
+		{"node.json", `{"blockchains": {"ethereum": {"health": {"interval": "10s"}}}, "nodes": [{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "health": {"call_timeout": "12s"}}]}`, 1, true},
+		{"threshold.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "health": {"healthy_threshold": -1}}]`, 1, true},
+		{"duration.json", `[{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545", "health": {"interval": 10}}]`, 0, false},
+	}
+	for _, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, c.name, c.content), true)
+		if (err == nil) != c.parsed {
+			t.Fatalf("Unexpected parse result for %s, err: %v", c.name, err)
+		}
+		if !c.parsed {
+			continue
+		}
+		_, err = list.Validate(false)
+		if errs, _ := err.(ConfigErrors); len(errs) != c.errors {
+			t.Fatalf("Expected %d errors for %s, got %v", c.errors, c.name, err)
+		}
+	}
+}
+
+// connectionsConfig returns default configuration with connections settings
+func connectionsConfig(retries int, retriesInterval, keepAlive time.Duration) Config {
+	config := DefaultConfig()
+	config.ConnectionRetries = retries
+	config.ConnectionRetriesInterval = retriesInterval
+	config.ClientNodeKeepAlive = keepAlive
+	return config
+}
+
+func TestLoadConfigConnections(t *testing.T) {
+	var cases = []struct {
+		env      map[string]string
+		expected Config
+		errors   []string
+	}{
+		{map[string]string{}, DefaultConfig(), nil},
+		{
+			map[string]string{"NB_CONNECTION_RETRIES": "5", "NB_CONNECTION_RETRIES_INTERVAL": "250ms", "NB_CLIENT_NODE_KEEP_ALIVE": "2m"},
+			connectionsConfig(5, 250*time.Millisecond, 2*time.Minute),
+			nil,
+		},
+		{
+			map[string]string{"NB_CONNECTION_RETRIES": "1", "NB_CONNECTION_RETRIES_INTERVAL": "2s"},
+			connectionsConfig(1, 2*time.Second, 5*time.Second),
+			nil,
+		},
+		{map[string]string{"NB_CONNECTION_RETRIES": "11"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES"}},
+		// Requests are not routed without at least one attempt
+		{map[string]string{"NB_CONNECTION_RETRIES": "0"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES"}},
+		{map[string]string{"NB_CONNECTION_RETRIES": "-1"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES"}},
+		{map[string]string{"NB_CONNECTION_RETRIES": "two"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES"}},
+		{map[string]string{"NB_CONNECTION_RETRIES_INTERVAL": "31s"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES_INTERVAL"}},
+		{map[string]string{"NB_CONNECTION_RETRIES_INTERVAL": "500us"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES_INTERVAL"}},
+		// Durations without units are rejected
+		{map[string]string{"NB_CONNECTION_RETRIES_INTERVAL": "10"}, DefaultConfig(), []string{"NB_CONNECTION_RETRIES_INTERVAL"}},
+		{
+			map[string]string{"NB_CONNECTION_RETRIES": "20", "NB_CLIENT_NODE_KEEP_ALIVE": "0s"},
+			DefaultConfig(),
+			[]string{"NB_CONNECTION_RETRIES", "NB_CLIENT_NODE_KEEP_ALIVE"},
+		},
+	}
+	for i, c := range cases {
+		for _, name := range []string{"NB_CONNECTION_RETRIES", "NB_CONNECTION_RETRIES_INTERVAL", "NB_CLIENT_NODE_KEEP_ALIVE"} {
+			t.Setenv(name, c.env[name])
+		}
+
+		config, err := LoadConfig()
+		if !reflect.DeepEqual(config, c.expected) {
+			t.Fatalf("Case %d: expected %+v, got %+v", i, c.expected, config)
+		}
+		if c.errors == nil {
+			if err != nil {
+				t.Fatalf("Case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		errs, ok := err.(ConfigErrors)
+		if !ok || len(errs) != len(c.errors) {
+			t.Fatalf("Case %d: expected errors for %v, got %v", i, c.errors, err)
+		}
+		for j, name := range c.errors {
+			if !strings.HasPrefix(errs[j], name+" ") {
+				t.Fatalf("Case %d: expected error for %s, got %s", i, name, errs[j])
+			}
+		}
+	}
+}
+
+// configEnv lists all environment variables read by LoadConfig
+var configEnv = []string{
+	"BUGOUT_AUTH_URL", "BUGOUT_AUTH_CALL_TIMEOUT", "NB_APPLICATION_ID", "NB_CONTROLLER_TOKEN", "NB_CONTROLLER_ACCESS_ID",
+	"NB_CONNECTION_RETRIES", "NB_CONNECTION_RETRIES_INTERVAL", "NB_CLIENT_NODE_KEEP_ALIVE",
+	"NB_ACCESS_ID_HEADER", "NB_DATA_SOURCE_HEADER", "NB_NODE_TAGS_HEADER",
+	"MOONSTREAM_NODES", "MOONSTREAM_NODES_SOURCE", "MOONSTREAM_NODES_JOURNAL_ID", "NB_NODES_REFRESH_INTERVAL",
+	"NB_KNOWN_BLOCKCHAINS", "NB_DNS_REFRESH_INTERVAL", "HUMBUG_REPORTER_NB_TOKEN", "NB_SERVER_PORT",
+	"MOONSTREAM_DB_URI", "MOONSTREAM_DB_URI_READ_ONLY", "MOONSTREAM_DB_MAX_IDLE_CONNS",
+	"MOONSTREAM_DB_MAX_OPEN_CONNS", "MOONSTREAM_DB_CONN_MAX_LIFETIME", "MOONSTREAM_DB_CONN_MAX_IDLE_TIME",
+}
+
+// setConfigEnv sets environment variables read by LoadConfig, not listed are cleared
+func setConfigEnv(t *testing.T, env map[string]string) {
+	for _, name := range configEnv {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	setConfigEnv(t, map[string]string{
+		"BUGOUT_AUTH_URL":                  "https://auth.bugout.dev",
+		"BUGOUT_AUTH_CALL_TIMEOUT":         "3s",
+		"NB_APPLICATION_ID":                "0d6e4d3f-6b3e-4b5c-9a9e-6f1c2a7b8c90",
+		"NB_CONTROLLER_TOKEN":              "controller-token",
+		"NB_CONTROLLER_ACCESS_ID":          "4f5cd2d4-b7d3-4f8e-9a4a-1f2b6f0b3c1d",
+		"NB_CONNECTION_RETRIES":            "3",
+		"NB_CONNECTION_RETRIES_INTERVAL":   "50ms",
+		"NB_CLIENT_NODE_KEEP_ALIVE":        "10s",
+		"NB_ACCESS_ID_HEADER":              "x-moonstream-access-id",
+		"NB_DATA_SOURCE_HEADER":            "x-moonstream-data-source",
+		"NB_NODE_TAGS_HEADER":              "x-moonstream-node-tags",
+		"MOONSTREAM_NODES":                 "ethereum,10.0.0.5,8545",
+		"MOONSTREAM_NODES_SOURCE":          "bugout",
+		"MOONSTREAM_NODES_JOURNAL_ID":      "journal-1",
+		"NB_NODES_REFRESH_INTERVAL":        "5m",
+		"NB_KNOWN_BLOCKCHAINS":             "ethereum, solana,",
+		"NB_DNS_REFRESH_INTERVAL":          "0s",
+		"HUMBUG_REPORTER_NB_TOKEN":         "humbug-token",
+		"NB_SERVER_PORT":                   "9544",
+		"MOONSTREAM_DB_URI":                "postgres://rw@localhost/moonstream",
+		"MOONSTREAM_DB_URI_READ_ONLY":      "postgres://ro@localhost/moonstream",
+		"MOONSTREAM_DB_MAX_IDLE_CONNS":     "5",
+		"MOONSTREAM_DB_MAX_OPEN_CONNS":     "20",
+		"MOONSTREAM_DB_CONN_MAX_LIFETIME":  "1h",
+		"MOONSTREAM_DB_CONN_MAX_IDLE_TIME": "90s",
+	})
+	expected := Config{
+		BugoutAuthURL:         "https://auth.bugout.dev",
+		BugoutAuthCallTimeout: 3 * time.Second,
+		ApplicationID:         "0d6e4d3f-6b3e-4b5c-9a9e-6f1c2a7b8c90",
+		ControllerToken:       "controller-token",
+		ControllerAccessID:    "4f5cd2d4-b7d3-4f8e-9a4a-1f2b6f0b3c1d",
+
+		ConnectionRetries:         3,
+		ConnectionRetriesInterval: 50 * time.Millisecond,
+		ClientNodeKeepAlive:       10 * time.Second,
+
+		AccessIDHeader:   "X-Moonstream-Access-Id",
+		DataSourceHeader: "X-Moonstream-Data-Source",
+		NodeTagsHeader:   "X-Moonstream-Node-Tags",
+
+		Nodes:                "ethereum,10.0.0.5,8545",
+		NodesSource:          NodesSourceBugout,
+		NodesJournalID:       "journal-1",
+		NodesRefreshInterval: 5 * time.Minute,
+		KnownBlockchains:     []string{"ethereum", "solana"},
+		DNSRefreshInterval:   0,
+
+		HumbugReporterToken: "humbug-token",
+
+		ServerPort: 9544,
+
+		Database: DatabaseConfig{
+			URI: "postgres://rw@localhost/moonstream", URIReadOnly: "postgres://ro@localhost/moonstream",
+			MaxIdleConns: 5, MaxOpenConns: 20, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: 90 * time.Second,
+		},
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Unable to load configuration, err: %v", err)
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, config)
+	}
+
+	// Deprecated variables follow configuration
+	defer SetConfig(appConfig)
+	SetConfig(config)
+	if NB_CONTROLLER_TOKEN != "controller-token" || NB_CLIENT_NODE_KEEP_ALIVE != 10 || MOONSTREAM_DB_MAX_IDLE_CONNS != 5 {
+		t.Fatal("Expected deprecated variables set from configuration")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	var cases = []struct {
+		env    map[string]string
+		errors []string
+	}{
+		{map[string]string{"BUGOUT_AUTH_URL": "auth.bugout.dev"}, []string{"BUGOUT_AUTH_URL"}},
+		{map[string]string{"BUGOUT_AUTH_URL": "ftp://auth.bugout.dev"}, []string{"BUGOUT_AUTH_URL"}},
+		{map[string]string{"BUGOUT_AUTH_CALL_TIMEOUT": "2m"}, []string{"BUGOUT_AUTH_CALL_TIMEOUT"}},
+		{map[string]string{"NB_APPLICATION_ID": "application"}, []string{"NB_APPLICATION_ID"}},
+		{map[string]string{"NB_CONTROLLER_ACCESS_ID": "4f5cd2d4"}, []string{"NB_CONTROLLER_ACCESS_ID"}},
+		{map[string]string{"NB_CLIENT_NODE_KEEP_ALIVE": "2h"}, []string{"NB_CLIENT_NODE_KEEP_ALIVE"}},
+		{map[string]string{"MOONSTREAM_NODES_SOURCE": "consul"}, []string{"MOONSTREAM_NODES_SOURCE"}},
+		{
+			map[string]string{"MOONSTREAM_NODES_SOURCE": "bugout"},
+			[]string{"MOONSTREAM_NODES_JOURNAL_ID", "NB_CONTROLLER_TOKEN"},
+		},
+		{map[string]string{"NB_NODES_REFRESH_INTERVAL": "-1m"}, []string{"NB_NODES_REFRESH_INTERVAL"}},
+		{map[string]string{"NB_KNOWN_BLOCKCHAINS": " , "}, []string{"NB_KNOWN_BLOCKCHAINS"}},
+		{map[string]string{"NB_DNS_REFRESH_INTERVAL": "often"}, []string{"NB_DNS_REFRESH_INTERVAL"}},
+		{map[string]string{"NB_SERVER_PORT": "0"}, []string{"NB_SERVER_PORT"}},
+		{map[string]string{"NB_SERVER_PORT": "65536"}, []string{"NB_SERVER_PORT"}},
+		// All invalid variables are reported at once
+		{
+			map[string]string{"NB_APPLICATION_ID": "application", "NB_SERVER_PORT": "http", "MOONSTREAM_DB_MAX_IDLE_CONNS": "-1"},
+			[]string{"NB_APPLICATION_ID", "NB_SERVER_PORT", "MOONSTREAM_DB_MAX_IDLE_CONNS"},
+		},
+	}
+	for i, c := range cases {
+		setConfigEnv(t, c.env)
+
+		_, err := LoadConfig()
+		errs, _ := err.(ConfigErrors)
+		if len(errs) != len(c.errors) {
+			t.Fatalf("Case %d: expected errors for %v, got %v", i, c.errors, err)
+		}
+		for j, name := range c.errors {
+			if !strings.HasPrefix(errs[j], name+" ") {
+				t.Fatalf("Case %d: expected error for %s, got %s", i, name, errs[j])
+			}
+		}
+	}
+}
+
+func TestConfigErrorsFilter(t *testing.T) {
+	setConfigEnv(t, map[string]string{
+		"NB_APPLICATION_ID":     "application",
+		"NB_SERVER_PORT":        "0",
+		"NB_ACCESS_ID_HEADER":   "x-node-balancer-node-tags",
+		"NB_DATA_SOURCE_HEADER": "x-node-balancer-data-source",
+	})
+
+	_, err := LoadConfig()
+	errs, _ := err.(ConfigErrors)
+	if len(errs) != 3 {
+		t.Fatalf("Expected 3 errors, got %v", err)
+	}
+	if filtered := errs.Filter("NB_APPLICATION_ID", "NB_CONTROLLER_TOKEN"); len(filtered) != 1 || filtered[0] != errs[0] {
+		t.Fatalf("Expected NB_APPLICATION_ID error only, got %v", filtered)
+	}
+	if filtered := errs.Filter("NB_ACCESS_ID_HEADER"); len(filtered) != 1 {
+		t.Fatalf("Expected headers error, got %v", filtered)
+	}
+	if filtered := errs.Filter("NB_SERVER"); filtered != nil {
+		t.Fatalf("Expected no errors for variable prefix, got %v", filtered)
+	}
+}
+
+func TestLoadConfigDatabase(t *testing.T) {
+	var cases = []struct {
+		env      map[string]string
+		expected DatabaseConfig
+		errors   []string
+	}{
+		{map[string]string{}, DefaultConfig().Database, nil},
+		{
+			map[string]string{
+				"MOONSTREAM_DB_URI":                "postgres://rw@localhost/moonstream",
+				"MOONSTREAM_DB_URI_READ_ONLY":      "postgres://ro@localhost/moonstream",
+				"MOONSTREAM_DB_MAX_IDLE_CONNS":     "5",
+				"MOONSTREAM_DB_MAX_OPEN_CONNS":     "20",
+				"MOONSTREAM_DB_CONN_MAX_LIFETIME":  "1h",
+				"MOONSTREAM_DB_CONN_MAX_IDLE_TIME": "90s",
+			},
+			DatabaseConfig{
+				URI: "postgres://rw@localhost/moonstream", URIReadOnly: "postgres://ro@localhost/moonstream",
+				MaxIdleConns: 5, MaxOpenConns: 20, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: 90 * time.Second,
+			},
+			nil,
+		},
+		{
+			map[string]string{"MOONSTREAM_DB_MAX_IDLE_CONNS": "-1", "MOONSTREAM_DB_MAX_OPEN_CONNS": "many"},
+			DefaultConfig().Database,
+			[]string{"MOONSTREAM_DB_MAX_IDLE_CONNS", "MOONSTREAM_DB_MAX_OPEN_CONNS"},
+		},
+		{
+			map[string]string{"MOONSTREAM_DB_CONN_MAX_LIFETIME": "-5m", "MOONSTREAM_DB_CONN_MAX_IDLE_TIME": "30"},
+			DefaultConfig().Database,
+			[]string{"MOONSTREAM_DB_CONN_MAX_LIFETIME", "MOONSTREAM_DB_CONN_MAX_IDLE_TIME"},
+		},
+	}
+	for i, c := range cases {
+		for _, name := range []string{
+			"MOONSTREAM_DB_URI", "MOONSTREAM_DB_URI_READ_ONLY", "MOONSTREAM_DB_MAX_IDLE_CONNS",
+			"MOONSTREAM_DB_MAX_OPEN_CONNS", "MOONSTREAM_DB_CONN_MAX_LIFETIME", "MOONSTREAM_DB_CONN_MAX_IDLE_TIME",
+		} {
+			t.Setenv(name, c.env[name])
+		}
+
+		config, err := LoadConfig()
+		if config.Database != c.expected {
+			t.Fatalf("Case %d: expected %+v, got %+v", i, c.expected, config.Database)
+		}
+		errs, _ := err.(ConfigErrors)
+		if len(errs) != len(c.errors) {
+			t.Fatalf("Case %d: expected errors for %v, got %v", i, c.errors, err)
+		}
+		for j, name := range c.errors {
+			if !strings.HasPrefix(errs[j], name+" ") {
+				t.Fatalf("Case %d: expected error for %s, got %s", i, name, errs[j])
+			}
+		}
+	}
+}
+
+func TestLoadConfigHeaders(t *testing.T) {
+	var cases = []struct {
+		env      map[string]string
+		expected []string
+		errors   []string
+	}{
+		{map[string]string{}, []string{"X-Node-Balancer-Access-Id", "X-Node-Balancer-Data-Source", "X-Node-Balancer-Node-Tags"}, nil},
+		{
+			map[string]string{"NB_ACCESS_ID_HEADER": "x-moonstream-access-id", "NB_DATA_SOURCE_HEADER": "X-MOONSTREAM-SOURCE"},
+			[]string{"X-Moonstream-Access-Id", "X-Moonstream-Source", "X-Node-Balancer-Node-Tags"},
+			nil,
+		},
+		{
+			map[string]string{"NB_ACCESS_ID_HEADER": "x access id", "NB_DATA_SOURCE_HEADER": "x-data-source:", "NB_NODE_TAGS_HEADER": "x-tags\n"},
+			[]string{"X-Node-Balancer-Access-Id", "X-Node-Balancer-Data-Source", "X-Node-Balancer-Node-Tags"},
+			[]string{"NB_ACCESS_ID_HEADER", "NB_DATA_SOURCE_HEADER", "NB_NODE_TAGS_HEADER"},
+		},
+		{
+			map[string]string{"NB_NODE_TAGS_HEADER": "X-NODE-BALANCER-ACCESS-ID"},
+			[]string{"X-Node-Balancer-Access-Id", "X-Node-Balancer-Data-Source", "X-Node-Balancer-Access-Id"},
+			[]string{"NB_ACCESS_ID_HEADER, NB_DATA_SOURCE_HEADER and NB_NODE_TAGS_HEADER"},
+		},
+	}
+	for i, c := range cases {
+		for _, name := range []string{"NB_ACCESS_ID_HEADER", "NB_DATA_SOURCE_HEADER", "NB_NODE_TAGS_HEADER"} {
+			t.Setenv(name, c.env[name])
+		}
+
+		config, err := LoadConfig()
+		headers := []string{config.AccessIDHeader, config.DataSourceHeader, config.NodeTagsHeader}
+		if !reflect.DeepEqual(headers, c.expected) {
+			t.Fatalf("Case %d: expected headers %v, got %v", i, c.expected, headers)
+		}
+		errs, _ := err.(ConfigErrors)
+		if len(errs) != len(c.errors) {
+			t.Fatalf("Case %d: expected errors for %v, got %v", i, c.errors, err)
+		}
+		for j, name := range c.errors {
+			if !strings.HasPrefix(errs[j], name+" ") {
+				t.Fatalf("Case %d: expected error for %s, got %s", i, name, errs[j])
+			}
+		}
+	}
+
+	// Headers are matched regardless of case used by client
+	defer func(config Config) { appConfig = config }(appConfig)
+	t.Setenv("NB_ACCESS_ID_HEADER", "x-moonstream-access-id")
+	appConfig, _ = LoadConfig()
+	r := httptest.NewRequest("GET", "/nb/ethereum/jsonrpc", nil)
+	r.Header.Set("X-MOONSTREAM-ACCESS-ID", "4f5cd2d4-b7d3-4f8e-9a4a-1f2b6f0b3c1d")
+	if accessID := extractAccessID(r); accessID != "4f5cd2d4-b7d3-4f8e-9a4a-1f2b6f0b3c1d" {
+		t.Fatalf("Expected access id from custom header, got %s", accessID)
+	}
+}
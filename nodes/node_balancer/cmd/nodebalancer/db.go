@@ -11,30 +11,49 @@ var (
 	databaseClient DatabaseClient
 )
 
+// Client is used for read queries, WriteClient is set if read-write
+// database URI provided
 type DatabaseClient struct {
-	Client *sql.DB
+	Client      *sql.DB
+	WriteClient *sql.DB
 }
 
-// Establish connection with database
-func InitDatabaseClient() error {
-	db, err := sql.Open("postgres", MOONSTREAM_DB_URI_READ_ONLY)
-	if err != nil {
-		return fmt.Errorf("DSN parse error or another database initialization error: %v", err)
+// Establish connection with database, read queries go to read-only
+// database if it is provided and to read-write database otherwise
+func InitDatabaseClient(config DatabaseConfig) error {
+	if config.URI == "" && config.URIReadOnly == "" {
+		return fmt.Errorf("MOONSTREAM_DB_URI or MOONSTREAM_DB_URI_READ_ONLY should be set")
 	}
 
-	// Set the maximum number of concurrently idle connections,
-	// by default sql.DB allows a maximum of 2 idle connections.
-	db.SetMaxIdleConns(MOONSTREAM_DB_MAX_IDLE_CONNS)
+	var client DatabaseClient
+	if config.URI != "" {
+		db, err := openDatabase(config.URI, config)
+		if err != nil {
+			return err
+		}
+		client.WriteClient = db
+		client.Client = db
+	}
+	if config.URIReadOnly != "" {
+		db, err := openDatabase(config.URIReadOnly, config)
+		if err != nil {
+			return err
+		}
+		client.Client = db
+	}
+	databaseClient = client
 
-	// Set the maximum lifetime of a connection.
-	// Longer lifetime increase memory usage.
-	db.SetConnMaxLifetime(MOONSTREAM_DB_CONN_MAX_LIFETIME)
+	return nil
+}
 
-	databaseClient = DatabaseClient{
-		Client: db,
+func openDatabase(uri string, config DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", uri)
+	if err != nil {
+		return nil, fmt.Errorf("DSN parse error or another database initialization error: %v", err)
 	}
+	config.Apply(db)
 
-	return nil
+	return db, nil
 }
 
 type Block struct {
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInitDatabaseClient(t *testing.T) {
+	config := DatabaseConfig{MaxIdleConns: 2, MaxOpenConns: 10, ConnMaxLifetime: time.Minute}
+
+	if err := InitDatabaseClient(config); err == nil {
+		t.Fatal("Expected error without database URI")
+	}
+
+	// Read queries go to read-write database if read-only one not provided
+	config.URI = "postgres://rw@localhost/moonstream"
+	if err := InitDatabaseClient(config); err != nil {
+		t.Fatalf("Unable to initialize database client, err: %v", err)
+	}
+	if databaseClient.WriteClient == nil || databaseClient.Client != databaseClient.WriteClient {
+		t.Fatal("Expected read-write database used for read queries")
+	}
+	if databaseClient.Client.Stats().MaxOpenConnections != 10 {
+		t.Fatalf("Expected pool settings applied, got %+v", databaseClient.Client.Stats())
+	}
+
+	config.URIReadOnly = "postgres://ro@localhost/moonstream"
+	if err := InitDatabaseClient(config); err != nil {
+		t.Fatalf("Unable to initialize database client, err: %v", err)
+	}
+	if databaseClient.WriteClient == nil || databaseClient.Client == databaseClient.WriteClient {
+		t.Fatal("Expected read-only database used for read queries")
+	}
+}
@@ -116,7 +116,7 @@ func initCacheCleaning(debug bool) {
 func extractAccessID(r *http.Request) string {
 	var accessID string
 
-	accessIDHeaders := r.Header[strings.Title(NB_ACCESS_ID_HEADER)]
+	accessIDHeaders := r.Header[appConfig.AccessIDHeader]
 	for _, h := range accessIDHeaders {
 		accessID = h
 	}
@@ -135,7 +135,7 @@ func extractAccessID(r *http.Request) string {
 func extractDataSource(r *http.Request) string {
 	dataSource := "database"
 
-	dataSources := r.Header[strings.Title(NB_DATA_SOURCE_HEADER)]
+	dataSources := r.Header[appConfig.DataSourceHeader]
 	for _, h := range dataSources {
 		dataSource = h
 	}
@@ -150,6 +150,32 @@ func extractDataSource(r *http.Request) string {
 	return dataSource
 }
 
+// Extract node_tags from header and query. Query takes precedence over header.
+// Tags are passed as comma separated list.
+func extractNodeTags(r *http.Request) []string {
+	var nodeTagsRaw string
+
+	nodeTagsHeaders := r.Header[appConfig.NodeTagsHeader]
+	for _, h := range nodeTagsHeaders {
+		nodeTagsRaw = h
+	}
+
+	queries := r.URL.Query()
+	for k, v := range queries {
+		if k == "node_tags" {
+			nodeTagsRaw = v[0]
+		}
+	}
+
+	var nodeTags []string
+	for _, tag := range strings.Split(nodeTagsRaw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			nodeTags = append(nodeTags, tag)
+		}
+	}
+	return nodeTags
+}
+
 // Handle panic errors to prevent server shutdown
 func panicMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -267,7 +293,7 @@ func accessMiddleware(next http.Handler) http.Handler {
 		}
 
 		// If access id does not belong to internal crawlers, then check cache or find it in Bugout resources
-		if accessID == NB_CONTROLLER_ACCESS_ID {
+		if accessID == appConfig.ControllerAccessID {
 			if stateCLI.enableDebugFlag {
 				log.Printf("Access id belongs to internal crawlers")
 			}
@@ -285,8 +311,8 @@ func accessMiddleware(next http.Handler) http.Handler {
 				log.Printf("New access id, looking at Brood resources")
 			}
 			resources, err := bugoutClient.Brood.GetResources(
-				NB_CONTROLLER_TOKEN,
-				NB_APPLICATION_ID,
+				appConfig.ControllerToken,
+				appConfig.ApplicationID,
 				map[string]string{"access_id": accessID},
 			)
 			if err != nil {
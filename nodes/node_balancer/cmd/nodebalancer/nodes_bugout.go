@@ -0,0 +1,138 @@
+/*
+Nodes configuration stored at Bugout journal.
+*/
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bugout-dev/bugout-go/pkg/spire"
+)
+
+// Sources of nodes configuration for MOONSTREAM_NODES_SOURCE
+const (
+	NodesSourceFile   = "file"
+	NodesSourceBugout = "bugout"
+)
+
+// Tag of journal entries with node definition, entry content is JSON
+// object with the same fields as node at JSON configuration file
+const NodesBugoutTag = "type:node_balancer_node"
+
+// fetchBugoutNodeEntries returns all journal entries tagged as node definitions
+func fetchBugoutNodeEntries(journalID string) ([]spire.Entry, error) {
+	var entries []spire.Entry
+	for {
+		page, err := bugoutClient.Spire.SearchEntries(
+			appConfig.ControllerToken, journalID, fmt.Sprintf("tag:%s", NodesBugoutTag), 100, len(entries), nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to fetch nodes from Bugout journal %s, err: %v", journalID, err)
+		}
+		entries = append(entries, page.Results...)
+		if len(page.Results) == 0 || len(entries) >= page.TotalResults {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// bugoutNodesRevision returns hash of entries identifiers and update times,
+// it changes on any entry creation, update or deletion
+func bugoutNodesRevision(entries []spire.Entry) string {
+	var versions []string
+	for _, entry := range entries {
+		versions = append(versions, fmt.Sprintf("%s:%s", entry.Id, entry.UpdatedAt))
+	}
+	sort.Strings(versions)
+
+	hash := sha256.New()
+	for _, version := range versions {
+		hash.Write([]byte(version))
+		hash.Write([]byte{'\n'})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// ParseBugoutNodeConfigs converts journal entries to node configurations
+func ParseBugoutNodeConfigs(journalID string, entries []spire.Entry) (*NodeConfigList, error) {
+	source := fmt.Sprintf("bugout:%s", journalID)
+	list := &NodeConfigList{Source: source, Revision: bugoutNodesRevision(entries)}
+	for _, entry := range entries {
+		node, err := parseJSONNodeConfig([]byte(entry.Content), fmt.Sprintf("%s/%s", source, entry.Id), nil)
+		if err != nil {
+			return nil, err
+		}
+		list.Nodes = append(list.Nodes, node)
+	}
+	if len(list.Nodes) == 0 {
+		return nil, fmt.Errorf("No nodes found in configuration %s", source)
+	}
+
+	return list, nil
+}
+
+func loadBugoutNodeConfigList(journalID string) (*NodeConfigList, error) {
+	if journalID == "" {
+		return nil, fmt.Errorf("MOONSTREAM_NODES_JOURNAL_ID should be set for %s nodes source", NodesSourceBugout)
+	}
+	entries, err := fetchBugoutNodeEntries(journalID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseBugoutNodeConfigs(journalID, entries)
+}
+
+// RefreshBugoutNodes fetches nodes from Bugout journal and applies them if
+// entries changed since last load. On any error current configuration is kept.
+func RefreshBugoutNodes(journalID string, strict bool) (bool, error) {
+	entries, err := fetchBugoutNodeEntries(journalID)
+	if err != nil {
+		return false, err
+	}
+
+	nodesReloadMux.Lock()
+	defer nodesReloadMux.Unlock()
+
+	currentList := GetNodeConfigList()
+	if currentList != nil && currentList.Revision == bugoutNodesRevision(entries) {
+		return false, nil
+	}
+
+	list, err := ParseBugoutNodeConfigs(journalID, entries)
+	if err != nil {
+		return false, err
+	}
+	err = checkNodeConfigList(list, strict)
+	if err != nil {
+		return false, err
+	}
+	err = ApplyNodeConfigList(list)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// initBugoutNodesRefresh fetches nodes from Bugout journal each interval
+func initBugoutNodesRefresh(journalID string, interval time.Duration, strict bool) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-t.C:
+			changed, err := RefreshBugoutNodes(journalID, strict)
+			if err != nil {
+				log.Printf("Unable to refresh nodes configuration, current configuration kept, err: %v", err)
+			} else if changed {
+				log.Printf("Nodes configuration refreshed from Bugout journal %s", journalID)
+			}
+		}
+	}
+}
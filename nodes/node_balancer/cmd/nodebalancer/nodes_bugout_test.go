@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	bugout "github.com/bugout-dev/bugout-go/pkg"
+	"github.com/bugout-dev/bugout-go/pkg/spire"
+)
+
+// mockSpire serves search of journal entries with node definitions
+type mockSpire struct {
+	mux      sync.Mutex
+	entries  []spire.Entry
+	failing  bool
+	requests int
+}
+
+func (m *mockSpire) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.requests++
+	if m.failing {
+		http.Error(w, "Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Path != "/journals/journal-1/search" || r.Header.Get("Authorization") != "Bearer controller-token" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("q") != "tag:"+NodesBugoutTag {
+		http.Error(w, "Wrong query", http.StatusBadRequest)
+		return
+	}
+
+	// Serve entries by pages of one entry to check pagination
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	page := spire.EntryResultsPage{TotalResults: len(m.entries), Offset: offset}
+	if offset < len(m.entries) {
+		page.Results = m.entries[offset : offset+1]
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func (m *mockSpire) set(entries []spire.Entry, failing bool) {
+	m.mux.Lock()
+	m.entries = entries
+	m.failing = failing
+	m.mux.Unlock()
+}
+
+func TestBugoutNodesSource(t *testing.T) {
+	mock := &mockSpire{entries: []spire.Entry{
+		{Id: "entry-1", Content: `{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545"}`, UpdatedAt: "2022-03-01T10:00:00"},
+		{Id: "entry-2", Content: `{"blockchain": "ethereum", "address": "10.0.0.6", "port": 8545, "weight": 2}`, UpdatedAt: "2022-03-01T10:00:00"},
+	}}
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	defer func(client bugout.BugoutClient, config Config) {
+		bugoutClient, appConfig = client, config
+	}(bugoutClient, appConfig)
+	bugoutClient = bugout.BugoutClient{Spire: spire.NewClient(server.URL, time.Second)}
+	appConfig.ControllerToken = "controller-token"
+	appConfig.NodesSource = NodesSourceBugout
+	appConfig.NodesJournalID = "journal-1"
+	blockchainPool = BlockchainPool{}
+
+	// Initial load
+	if err := ReloadNodes("", false); err != nil {
+		t.Fatalf("Unable to load nodes from Bugout, err: %v", err)
+	}
+	list := GetNodeConfigList()
+	if list.Source != "bugout:journal-1" || len(list.Nodes) != 2 || list.Nodes[1].source != "bugout:journal-1/entry-2" {
+		t.Fatalf("Wrong nodes loaded from Bugout: %+v", list)
+	}
+	keptNode := blockchainPool.FindNode("ethereum", "http://10.0.0.5:8545")
+	if keptNode == nil || blockchainPool.FindNode("ethereum", "http://10.0.0.6:8545").GetWeight() != 2 {
+		t.Fatal("Nodes from Bugout not found at blockchain pool")
+	}
+
+	var cases = []struct {
+		entries   []spire.Entry
+		failing   bool
+		changed   bool
+		err       bool
+		endpoints []string
+	}{
+		// Entries not changed
+		{mock.entries, false, false, false, []string{"http://10.0.0.5:8545", "http://10.0.0.6:8545"}},
+		// Entry updated and entry deleted
+		{
+			[]spire.Entry{{Id: "entry-1", Content: `{"blockchain": "ethereum", "endpoint": "http://10.0.0.5:8545"}`, UpdatedAt: "2022-03-01T10:00:00"},
+				{Id: "entry-3", Content: `{"blockchain": "ethereum", "endpoint": "http://10.0.0.7:8545"}`, UpdatedAt: "2022-03-02T10:00:00"}},
+			false, true, false, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"},
+		},
+		// Bugout unavailable
+		{nil, true, false, true, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"}},
+		// No entries and malformed entry
+		{[]spire.Entry{}, false, false, true, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"}},
+		{[]spire.Entry{{Id: "entry-4", Content: `{"blockchain": "ethereum"`}}, false, false, true, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"}},
+		// Duplicated nodes are rejected by validation
+		{
+			[]spire.Entry{{Id: "entry-5", Content: `{"blockchain": "ethereum", "endpoint": "http://10.0.0.8:8545"}`},
+				{Id: "entry-6", Content: `{"blockchain": "ethereum", "endpoint": "http://10.0.0.8:8545"}`}},
+			false, false, true, []string{"http://10.0.0.5:8545", "http://10.0.0.7:8545"},
+		},
+	}
+	for i, c := range cases {
+		mock.set(c.entries, c.failing)
+		changed, err := RefreshBugoutNodes("journal-1", false)
+		if changed != c.changed || (err != nil) != c.err {
+			t.Fatalf("Case %d: expected changed %t and error %t, got %t and %v", i, c.changed, c.err, changed, err)
+		}
+		var endpoints []string
+		for _, np := range blockchainPool.snapshot() {
+			for _, n := range np.Nodes {
+				endpoints = append(endpoints, n.Endpoint.String())
+			}
+		}
+		if len(endpoints) != len(c.endpoints) || endpoints[0] != c.endpoints[0] || endpoints[1] != c.endpoints[1] {
+			t.Fatalf("Case %d: expected endpoints %v, got %v", i, c.endpoints, endpoints)
+		}
+	}
+	if blockchainPool.FindNode("ethereum", "http://10.0.0.5:8545") != keptNode {
+		t.Fatal("Expected node without changes kept at blockchain pool")
+	}
+
+	appConfig.NodesJournalID = ""
+	if _, err := LoadNodeConfigList("", false); err == nil {
+		t.Fatal("Expected error for Bugout source without journal")
+	}
+}
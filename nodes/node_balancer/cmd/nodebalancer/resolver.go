@@ -0,0 +1,135 @@
+/*
+Resolving of node hostnames.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"time"
+)
+
+// Resolver looks up addresses of node hostnames, it is replaced at tests
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var nodesResolver Resolver = net.DefaultResolver
+
+// resolveHost returns sorted list of host addresses
+func resolveHost(host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), NB_DNS_LOOKUP_TIMEOUT)
+	defer cancel()
+
+	addresses, err := nodesResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
+
+// diffAddresses returns addresses added to and removed from sorted list
+func diffAddresses(oldAddresses, newAddresses []string) (added, removed []string) {
+	oldSet := make(map[string]bool)
+	for _, address := range oldAddresses {
+		oldSet[address] = true
+	}
+	newSet := make(map[string]bool)
+	for _, address := range newAddresses {
+		newSet[address] = true
+		if !oldSet[address] {
+			added = append(added, address)
+		}
+	}
+	for _, address := range oldAddresses {
+		if !newSet[address] {
+			removed = append(removed, address)
+		}
+	}
+	return added, removed
+}
+
+// Addresses returns resolved addresses of node hostname, empty for nodes
+// defined with IP address
+func (node *Node) Addresses() (addresses []string) {
+	node.mux.RLock()
+	addresses = node.addresses
+	node.mux.RUnlock()
+	return addresses
+}
+
+// dialContext connects to resolved addresses of node instead of hostname,
+// so transport does not stick to addresses looked up by system once
+func (node *Node) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range node.Addresses() {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(address, port))
+			if err == nil {
+				return conn, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// RefreshAddresses resolves node hostname again and replaces addresses if
+// records changed, idle connections to previous addresses are closed
+func (node *Node) RefreshAddresses() error {
+	if node.hostname == "" || node.static {
+		return nil
+	}
+
+	addresses, err := resolveHost(node.hostname)
+	if err != nil {
+		return err
+	}
+	added, removed := diffAddresses(node.Addresses(), addresses)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	node.mux.Lock()
+	node.addresses = addresses
+	node.mux.Unlock()
+	node.transport.CloseIdleConnections()
+	log.Printf("Addresses of node %s changed, added: %v, removed: %v", node.Endpoint.Host, added, removed)
+
+	return nil
+}
+
+// RefreshAddresses resolves hostnames of all not static nodes, on lookup
+// failure node keeps previous addresses
+func (bpool *BlockchainPool) RefreshAddresses() {
+	for _, b := range bpool.snapshot() {
+		for _, n := range b.Nodes {
+			err := n.RefreshAddresses()
+			if err != nil {
+				log.Printf("Unable to resolve node %s, previous addresses kept, err: %v", n.Endpoint.Host, err)
+			}
+		}
+	}
+}
+
+// initDNSRefresh re-resolves node hostnames each interval
+func initDNSRefresh(interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-t.C:
+			blockchainPool.RefreshAddresses()
+		}
+	}
+}
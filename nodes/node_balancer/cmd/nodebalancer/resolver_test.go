@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type fakeResolver struct {
+	mux     sync.Mutex
+	records map[string][]string
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	addresses, ok := r.records[host]
+	if !ok {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return addresses, nil
+}
+
+func (r *fakeResolver) set(host string, addresses []string) {
+	r.mux.Lock()
+	r.records[host] = addresses
+	r.mux.Unlock()
+}
+
+func TestRefreshAddresses(t *testing.T) {
+	resolver := &fakeResolver{records: map[string][]string{
+		"node1.example.com": {"10.0.0.6", "10.0.0.5"},
+		"node2.example.com": {"10.0.0.7"},
+	}}
+	defer func(r Resolver) { nodesResolver = r }(nodesResolver)
+	nodesResolver = resolver
+
+	configPath := writeConfig(t, "nodes.json", `[
+		{"blockchain": "ethereum", "endpoint": "http://node1.example.com:8545"},
+		{"blockchain": "ethereum", "endpoint": "http://node2.example.com:8545", "static": true},
+		{"blockchain": "ethereum", "endpoint": "http://10.0.0.8:8545"}
+	]`)
+	list, err := ParseNodeConfigs(configPath, true)
+	if err != nil {
+		t.Fatalf("Unable to parse configuration, err: %v", err)
+	}
+	var nodes []*Node
+	for _, nodeConfig := range list.Nodes {
+		node, err := newNode(nodeConfig)
+		if err != nil {
+			t.Fatalf("Unable to create node, err: %v", err)
+		}
+		nodes = append(nodes, node)
+	}
+	bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: nodes}}}
+
+	var cases = []struct {
+		records  map[string][]string
+		expected [][]string
+	}{
+		// Records are not changed
+		{
+			nil,
+			[][]string{{"10.0.0.5", "10.0.0.6"}, {"10.0.0.7"}, nil},
+		},
+		// Failover of first node, static node is not refreshed
+		{
+			map[string][]string{"node1.example.com": {"10.0.1.5"}, "node2.example.com": {"10.0.1.7"}},
+			[][]string{{"10.0.1.5"}, {"10.0.0.7"}, nil},
+		},
+		// Lookup failure keeps previous addresses
+		{
+			map[string][]string{"node1.example.com": {}},
+			[][]string{{"10.0.1.5"}, {"10.0.0.7"}, nil},
+		},
+	}
+	for i, c := range cases {
+		for host, addresses := range c.records {
+			resolver.set(host, addresses)
+		}
+		bpool.RefreshAddresses()
+		for j, node := range nodes {
+			if !reflect.DeepEqual(node.Addresses(), c.expected[j]) {
+				t.Fatalf("Case %d: expected addresses %v for node %d, got %v", i, c.expected[j], j, node.Addresses())
+			}
+		}
+	}
+
+	unresolvedConfig := NodeConfig{Blockchain: "ethereum", Address: "node3.example.com", Port: 8545}
+	unresolvedConfig.complete()
+	if _, err := newNode(unresolvedConfig); err == nil {
+		t.Fatal("Expected error for node with unresolved hostname")
+	}
+}
+
+func TestNodeDialResolvedAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"0x10"}}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	resolver := &fakeResolver{records: map[string][]string{"node1.example.com": {"127.0.0.1"}}}
+	defer func(r Resolver) { nodesResolver = r }(nodesResolver)
+	nodesResolver = resolver
+
+	nodeConfig := NodeConfig{Blockchain: "ethereum", Endpoint: fmt.Sprintf("http://node1.example.com:%s", serverURL.Port())}
+	nodeConfig.complete()
+	node, err := newNode(nodeConfig)
+	if err != nil {
+		t.Fatalf("Unable to create node, err: %v", err)
+	}
+	bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: []*Node{node}}}}
+
+	bpool.HealthCheck()
+	if !node.IsAlive() || node.CurrentBlock != 16 {
+		t.Fatal("Expected node reached through resolved address")
+	}
+
+	// Server listens only 127.0.0.1, idle connection to previous address is closed
+	resolver.set("node1.example.com", []string{"127.0.0.2"})
+	bpool.RefreshAddresses()
+	bpool.HealthCheck()
+	if node.IsAlive() {
+		t.Fatal("Expected node is not alive after record change")
+	}
+}
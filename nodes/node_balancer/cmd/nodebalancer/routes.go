@@ -35,14 +35,14 @@ func lbHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	attempts := GetAttemptsFromContext(r)
-	if attempts > NB_CONNECTION_RETRIES {
+	if attempts > appConfig.ConnectionRetries {
 		log.Printf("Max attempts reached from %s %s, terminating", r.RemoteAddr, r.URL.Path)
 		http.Error(w, "Service not available", http.StatusServiceUnavailable)
 		return
 	}
 
 	var blockchain string
-	for b := range configBlockchains {
+	for b := range GetConfigBlockchains() {
 		if strings.HasPrefix(r.URL.Path, fmt.Sprintf("/nb/%s/", b)) {
 			blockchain = b
 			break
@@ -56,9 +56,20 @@ func lbHandler(w http.ResponseWriter, r *http.Request) {
 	// Chose one node
 	var node *Node
 	cpool := GetClientPool(blockchain)
+	if cpool == nil {
+		http.Error(w, fmt.Sprintf("Unacceptable blockchain provided %s", blockchain), http.StatusBadRequest)
+		return
+	}
+	nodeTags := extractNodeTags(r)
+	if len(nodeTags) > 0 && len(GetNodeConfigList().FilterByTags(blockchain, nodeTags)) == 0 {
+		http.Error(w, fmt.Sprintf("There are no %s nodes with tags %s", blockchain, strings.Join(nodeTags, ",")), http.StatusBadRequest)
+		return
+	}
+
 	node = cpool.GetClientNode(currentClientAccess.AccessID)
-	if node == nil {
-		node = blockchainPool.GetNextNode(blockchain)
+	// Node could be marked as not alive or removed from configuration during reload
+	if node == nil || !node.IsAlive() || !node.HasTags(nodeTags) {
+		node = blockchainPool.GetNextNode(blockchain, nodeTags)
 		if node == nil {
 			http.Error(w, "There are no nodes available", http.StatusServiceUnavailable)
 			return
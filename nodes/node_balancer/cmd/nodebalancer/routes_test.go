@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLbHandlerAttempts(t *testing.T) {
+	defer func(config Config) { appConfig = config }(appConfig)
+	appConfig = DefaultConfig()
+	blockchainPool = BlockchainPool{}
+
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": "0x1"}`))
+	}))
+	defer node.Close()
+	configPath := writeConfig(t, "nodes.json", fmt.Sprintf(`[{"blockchain": "ethereum", "endpoint": "%s"}]`, node.URL))
+	if err := ReloadNodes(configPath, false); err != nil {
+		t.Fatalf("Unable to load nodes, err: %v", err)
+	}
+
+	var cases = []struct {
+		retries  int
+		attempts int
+		status   int
+	}{
+		// Minimal number of retries allows first attempt
+		{1, 0, http.StatusOK},
+		{1, 2, http.StatusServiceUnavailable},
+		{2, 2, http.StatusOK},
+		{2, 3, http.StatusServiceUnavailable},
+	}
+	for i, c := range cases {
+		appConfig.ConnectionRetries = c.retries
+
+		ctx := context.WithValue(context.Background(), "currentClientAccess", ClientResourceData{
+			AccessID:         "test",
+			BlockchainAccess: true,
+			ExtendedMethods:  true,
+			dataSource:       "blockchain",
+		})
+		if c.attempts > 0 {
+			ctx = context.WithValue(ctx, Attempts, c.attempts)
+		}
+		body := strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "eth_blockNumber", "params": []}`)
+		r := httptest.NewRequest("POST", "/nb/ethereum/jsonrpc", body).WithContext(ctx)
+		w := httptest.NewRecorder()
+		lbHandler(w, r)
+		if w.Code != c.status {
+			t.Fatalf("Case %d: expected status %d, got %d: %s", i, c.status, w.Code, w.Body.String())
+		}
+	}
+}
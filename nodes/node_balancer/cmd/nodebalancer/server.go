@@ -8,11 +8,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 
 	humbug "github.com/bugout-dev/humbug/go/pkg"
@@ -22,22 +26,45 @@ import (
 var (
 	internalCrawlersAccess ClientResourceData
 
-	configBlockchains map[string]bool
+	configBlockchains    map[string]bool
+	configBlockchainsMux sync.RWMutex
+
+	// Serializes nodes configuration reloads from signal and Bugout refresh
+	nodesReloadMux sync.Mutex
 
 	// Crash reporter
 	reporter *humbug.HumbugReporter
 )
 
+// GetConfigBlockchains returns set of blockchains from current configuration,
+// set replaced on configuration reload and should not be modified
+func GetConfigBlockchains() map[string]bool {
+	configBlockchainsMux.RLock()
+	defer configBlockchainsMux.RUnlock()
+
+	return configBlockchains
+}
+
 // initHealthCheck runs a routine for check status of the nodes every 5 seconds
 func initHealthCheck(debug bool) {
-	t := time.NewTicker(NB_HEALTH_CHECK_INTERVAL)
+	t := time.NewTicker(NB_HEALTH_CHECK_TICK)
+	lastClientsCheck := time.Now()
 	for {
 		select {
-		case <-t.C:
-			blockchainPool.HealthCheck()
+		case now := <-t.C:
+			// Nodes are checked with their own intervals
+			blockchainPool.HealthCheckDue(now)
+			if now.Sub(lastClientsCheck) < NB_HEALTH_CHECK_INTERVAL {
+				continue
+			}
+			lastClientsCheck = now
+
 			logStr := "Client pool healthcheck."
-			for b := range configBlockchains {
-				cp := clientPool[b]
+			for b := range GetConfigBlockchains() {
+				cp := GetClientPool(b)
+				if cp == nil {
+					continue
+				}
 				clients := cp.CleanInactiveClientNodes()
 				logStr += fmt.Sprintf(" Active %s clients: %d.", b, clients)
 			}
@@ -75,13 +102,13 @@ func GetRetryFromContext(r *http.Request) int {
 func proxyErrorHandler(proxy *httputil.ReverseProxy, url *url.URL) {
 	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, e error) {
 		retries := GetRetryFromContext(r)
-		if retries < NB_CONNECTION_RETRIES {
+		if retries < appConfig.ConnectionRetries {
 			log.Printf(
 				"An error occurred while proxying to %s, number of retries: %d/%d, err: %v",
-				url, retries+1, NB_CONNECTION_RETRIES, e.Error(),
+				url, retries+1, appConfig.ConnectionRetries, e.Error(),
 			)
 			select {
-			case <-time.After(NB_CONNECTION_RETRIES_INTERVAL):
+			case <-time.After(appConfig.ConnectionRetriesInterval):
 				ctx := context.WithValue(r.Context(), Retry, retries+1)
 				proxy.ServeHTTP(w, r.WithContext(ctx))
 			}
@@ -104,6 +131,183 @@ func proxyErrorHandler(proxy *httputil.ReverseProxy, url *url.URL) {
 	}
 }
 
+// newNode creates node with reverse proxy to endpoint from configuration
+func newNode(nodeConfig NodeConfig) (*Node, error) {
+	endpoint, err := url.Parse(nodeConfig.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := nodeConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if nodeConfig.InsecureSkipVerify {
+		log.Printf("WARNING! TLS certificate verification disabled for node %s, it is insecure", endpoint.Host)
+	}
+	var wsEndpoint *url.URL
+	if nodeConfig.HasWebSocket() {
+		wsEndpoint, err = url.Parse(nodeConfig.WSURL())
+		if err != nil {
+			return nil, err
+		}
+	}
+	node := &Node{
+		Endpoint:   endpoint,
+		WSEndpoint: wsEndpoint,
+		Alive:      true,
+		Weight:     nodeConfig.Weight,
+		Tags:       nodeConfig.Tags,
+
+		connKey: nodeConnKey(nodeConfig),
+		health:  DefaultHealthConfig().override(nodeConfig.Health),
+	}
+
+	// Modified structure of DefaultTransport net/http/transport/DefaultTransport,
+	// it could be extended if required detailed timeout configuration
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Hostnames are resolved by balancer to follow records changes, endpoint
+	// host is kept for TLS verification
+	if net.ParseIP(nodeConfig.Address) == nil {
+		node.hostname = nodeConfig.Address
+		node.static = nodeConfig.Static
+		node.addresses, err = resolveHost(node.hostname)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to resolve node %s, err: %v", node.hostname, err)
+		}
+		transport.DialContext = node.dialContext(&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		})
+	}
+	node.transport = transport
+
+	proxyToEndpoint := httputil.NewSingleHostReverseProxy(httpURL(endpoint))
+	proxyToEndpoint.Transport = transport
+	director := proxyToEndpoint.Director
+	proxyToEndpoint.Director = func(r *http.Request) {
+		director(r)
+		// Overwrite Query and Headers to not bypass nodebalancer Query and Headers
+		r.URL.RawQuery = ""
+		r.Header.Del(appConfig.AccessIDHeader)
+		r.Header.Del(appConfig.DataSourceHeader)
+		r.Header.Del(appConfig.NodeTagsHeader)
+		// Change r.Host from nodebalancer's to end host so TLS check will be passed
+		r.Host = r.URL.Host
+	}
+	proxyErrorHandler(proxyToEndpoint, endpoint)
+	node.GethReverseProxy = proxyToEndpoint
+
+	return node, nil
+}
+
+// nodeConnKey represents TLS, resolving and WebSocket options of node to detect changes on reload
+func nodeConnKey(nodeConfig NodeConfig) string {
+	return fmt.Sprintf("%t,%s,%t,%s", nodeConfig.InsecureSkipVerify, nodeConfig.CABundle, nodeConfig.Static, nodeConfig.WSURL())
+}
+
+// ReloadNodes parses nodes configuration and atomically replaces nodes at blockchain pool.
+// On any error current configuration remains untouched.
+func ReloadNodes(configPath string, strict bool) error {
+	nodesReloadMux.Lock()
+	defer nodesReloadMux.Unlock()
+
+	newNodeConfigList, err := LoadNodeConfigList(configPath, strict)
+	if err != nil {
+		return err
+	}
+
+	return ApplyNodeConfigList(newNodeConfigList)
+}
+
+// ApplyNodeConfigList atomically replaces nodes at blockchain pool with validated list.
+// Nodes with the same blockchain and endpoint are kept with their state, removed nodes
+// are not used for new requests, but already proxied requests are finished.
+func ApplyNodeConfigList(newNodeConfigList *NodeConfigList) error {
+	// Nodes are created before kept nodes are changed, so rejected
+	// configuration does not affect running nodes
+	nodes := make([]*Node, len(newNodeConfigList.Nodes))
+	for i, nodeConfig := range newNodeConfigList.Nodes {
+		node := blockchainPool.FindNode(nodeConfig.Blockchain, nodeConfig.URL())
+		// Node with changed TLS or resolving options is created from scratch
+		if node != nil && node.connKey == nodeConnKey(nodeConfig) {
+			continue
+		}
+		node, err := newNode(nodeConfig)
+		if err != nil {
+			return fmt.Errorf("Unable to create node %d from configuration %s, err: %v", i, newNodeConfigList.Source, err)
+		}
+		nodes[i] = node
+	}
+
+	var nodePools []*NodePool
+	newConfigBlockchains := make(map[string]bool)
+	for i, nodeConfig := range newNodeConfigList.Nodes {
+		node := nodes[i]
+		if node != nil {
+			log.Printf(
+				"Added new %s proxy blockchain under index %d from config file with geth url: %s://%s and weight %d",
+				nodeConfig.Blockchain, i, node.Endpoint.Scheme, node.Endpoint.Host, nodeConfig.Weight)
+		} else {
+			node = blockchainPool.FindNode(nodeConfig.Blockchain, nodeConfig.URL())
+			node.SetWeight(nodeConfig.Weight)
+			node.SetTags(nodeConfig.Tags)
+		}
+		node.SetHealthConfig(newNodeConfigList.EffectiveHealthConfig(nodeConfig.Blockchain, &newNodeConfigList.Nodes[i]))
+
+		// Append to supported blockchain set
+		newConfigBlockchains[nodeConfig.Blockchain] = true
+
+		var nodePool *NodePool
+		for _, np := range nodePools {
+			if np.Blockchain == nodeConfig.Blockchain {
+				nodePool = np
+			}
+		}
+		if nodePool == nil {
+			nodePool = &NodePool{Blockchain: nodeConfig.Blockchain}
+			nodePools = append(nodePools, nodePool)
+		}
+		nodePool.Nodes = append(nodePool.Nodes, node)
+	}
+
+	removedNodes := blockchainPool.ReplaceNodePools(nodePools)
+	for _, node := range removedNodes {
+		// Stop routing new requests to removed node, in-flight requests are drained
+		node.SetAlive(false)
+		log.Printf("Removed node %s from blockchain pool", node.Endpoint.Host)
+	}
+
+	configBlockchainsMux.Lock()
+	configBlockchains = newConfigBlockchains
+	configBlockchainsMux.Unlock()
+	UpdateClientPools()
+
+	SetNodeConfigList(newNodeConfigList)
+
+	for b := range newConfigBlockchains {
+		log.Printf("Nodes of %s blockchain with weights: %v", b, newNodeConfigList.Weights(b))
+	}
+
+	return nil
+}
+
+// initNodesReload reloads nodes configuration on SIGHUP signal
+func initNodesReload(configPath string, strict bool) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+	for range sigs {
+		log.Printf("Received SIGHUP, reloading nodes configuration from %s", configPath)
+		err := ReloadNodes(configPath, strict)
+		if err != nil {
+			log.Printf("Unable to reload nodes configuration, current configuration kept, err: %v", err)
+			continue
+		}
+		log.Printf("Nodes configuration reloaded from %s", configPath)
+	}
+}
+
 func Server() {
 	// Create Access ID cache
 	CreateAccessCache()
@@ -112,7 +316,7 @@ func Server() {
 	var err error
 	sessionID := uuid.New().String()
 	consent := humbug.CreateHumbugConsent(humbug.True)
-	reporter, err = humbug.CreateHumbugReporter(consent, "moonstream-node-balancer", sessionID, HUMBUG_REPORTER_NB_TOKEN)
+	reporter, err = humbug.CreateHumbugReporter(consent, "moonstream-node-balancer", sessionID, appConfig.HumbugReporterToken)
 	if err != nil {
 		fmt.Printf("Invalid Humbug Crash configuration, err: %v\n", err)
 		os.Exit(1)
@@ -121,9 +325,9 @@ func Server() {
 	reporter.Publish(humbug.SystemReport())
 
 	resources, err := bugoutClient.Brood.GetResources(
-		NB_CONTROLLER_TOKEN,
-		NB_APPLICATION_ID,
-		map[string]string{"access_id": NB_CONTROLLER_ACCESS_ID},
+		appConfig.ControllerToken,
+		appConfig.ApplicationID,
+		map[string]string{"access_id": appConfig.ControllerAccessID},
 	)
 	if err != nil {
 		fmt.Printf("Unable to get user with provided access identifier, err: %v\n", err)
@@ -157,59 +361,31 @@ func Server() {
 		resources.Resources[0].Id, clientAccess.BlockchainAccess, clientAccess.ExtendedMethods,
 	)
 
-	err = InitDatabaseClient()
+	err = InitDatabaseClient(appConfig.Database)
 	if err != nil {
 		log.Printf("Unable to initialize database connection, err: %v", err)
 	} else {
 		log.Printf("Connection with database established")
 	}
 
-	// Fill NodeConfigList with initial nodes from environment variables
-	err = LoadConfig(stateCLI.configPathFlag)
+	log.Printf(
+		"Request headers: access id %s, data source %s, node tags %s",
+		appConfig.AccessIDHeader, appConfig.DataSourceHeader, appConfig.NodeTagsHeader,
+	)
+
+	// Fill NodeConfigList with initial nodes from configuration file
+	err = ReloadNodes(stateCLI.configPathFlag, stateCLI.strictConfigFlag)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	configBlockchains = make(map[string]bool)
-
-	// Parse nodes and set list of proxies
-	for i, nodeConfig := range nodeConfigs {
-		endpoint, err := url.Parse(nodeConfig.Endpoint)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		// Append to supported blockchain set
-		configBlockchains[nodeConfig.Blockchain] = true
-
-		proxyToEndpoint := httputil.NewSingleHostReverseProxy(endpoint)
-		// If required detailed timeout configuration, define node.GethReverseProxy.Transport = &http.Transport{}
-		// as modified structure of DefaultTransport net/http/transport/DefaultTransport
-		director := proxyToEndpoint.Director
-		proxyToEndpoint.Director = func(r *http.Request) {
-			director(r)
-			// Overwrite Query and Headers to not bypass nodebalancer Query and Headers
-			r.URL.RawQuery = ""
-			r.Header.Del(strings.Title(NB_ACCESS_ID_HEADER))
-			r.Header.Del(strings.Title(NB_DATA_SOURCE_HEADER))
-			// Change r.Host from nodebalancer's to end host so TLS check will be passed
-			r.Host = r.URL.Host
-		}
-		proxyErrorHandler(proxyToEndpoint, endpoint)
-
-		blockchainPool.AddNode(&Node{
-			Endpoint:         endpoint,
-			Alive:            true,
-			GethReverseProxy: proxyToEndpoint,
-		}, nodeConfig.Blockchain)
-		log.Printf(
-			"Added new %s proxy blockchain under index %d from config file with geth url: %s://%s",
-			nodeConfig.Blockchain, i, endpoint.Scheme, endpoint.Host)
+	go initNodesReload(stateCLI.configPathFlag, stateCLI.strictConfigFlag)
+	if appConfig.NodesSource == NodesSourceBugout && appConfig.NodesRefreshInterval > 0 {
+		go initBugoutNodesRefresh(appConfig.NodesJournalID, appConfig.NodesRefreshInterval, stateCLI.strictConfigFlag)
+	}
+	if appConfig.DNSRefreshInterval > 0 {
+		go initDNSRefresh(appConfig.DNSRefreshInterval)
 	}
-
-	// Generate map of clients
-	CreateClientPools()
 
 	serveMux := http.NewServeMux()
 	serveMux.Handle("/nb/", accessMiddleware(http.HandlerFunc(lbHandler)))
@@ -220,8 +396,12 @@ func Server() {
 	commonHandler := logMiddleware(serveMux)
 	commonHandler = panicMiddleware(commonHandler)
 
+	listeningPort := stateCLI.listeningPortFlag
+	if listeningPort == "" {
+		listeningPort = strconv.Itoa(appConfig.ServerPort)
+	}
 	server := http.Server{
-		Addr:         fmt.Sprintf("%s:%s", stateCLI.listeningAddrFlag, stateCLI.listeningPortFlag),
+		Addr:         net.JoinHostPort(stateCLI.listeningAddrFlag, listeningPort),
 		Handler:      commonHandler,
 		ReadTimeout:  40 * time.Second,
 		WriteTimeout: 40 * time.Second,
@@ -236,7 +416,7 @@ func Server() {
 	// Start access id cache cleaning
 	go initCacheCleaning(stateCLI.enableDebugFlag)
 
-	log.Printf("Starting node load balancer HTTP server at %s:%s", stateCLI.listeningAddrFlag, stateCLI.listeningPortFlag)
+	log.Printf("Starting node load balancer HTTP server at %s", server.Addr)
 	err = server.ListenAndServe()
 	if err != nil {
 		fmt.Printf("Failed to start server listener, err: %v\n", err)
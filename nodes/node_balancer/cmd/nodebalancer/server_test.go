@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestReloadNodes(t *testing.T) {
+	blockchainPool = BlockchainPool{}
+
+	if err := ReloadNodes("testdata/nodes.json", false); err != nil {
+		t.Fatalf("Unable to load nodes, err: %v", err)
+	}
+	keptNode := blockchainPool.FindNode("ethereum", "http://10.0.0.5:8545")
+	removedNode := blockchainPool.FindNode("ethereum", "http://10.0.0.6:8545")
+	if keptNode == nil || removedNode == nil {
+		t.Fatal("Nodes from configuration not found at blockchain pool")
+	}
+
+	// Readers should see either full old or full new configuration
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				nodesNum := 0
+				for _, np := range blockchainPool.snapshot() {
+					nodesNum += len(np.Nodes)
+				}
+				if nodesNum != 3 && nodesNum != 2 {
+					t.Errorf("Partially built blockchain pool with %d nodes", nodesNum)
+					return
+				}
+				if l := len(GetNodeConfigList().Nodes); l != 3 && l != 2 {
+					t.Errorf("Partially built node config list with %d nodes", l)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		configPath := "testdata/nodes_reload.json"
+		if i%2 == 1 {
+			configPath = "testdata/nodes.json"
+		}
+		if err := ReloadNodes(configPath, false); err != nil {
+			t.Fatalf("Unable to reload nodes from %s, err: %v", configPath, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := ReloadNodes("testdata/nodes_reload.json", false); err != nil {
+		t.Fatalf("Unable to reload nodes, err: %v", err)
+	}
+	if blockchainPool.FindNode("ethereum", "http://10.0.0.5:8545") != keptNode {
+		t.Fatal("Node presented in both configurations was recreated")
+	}
+	if blockchainPool.FindNode("ethereum", "http://10.0.0.7:8545") == nil {
+		t.Fatal("New node was not added")
+	}
+	if removedNode.IsAlive() {
+		t.Fatal("Removed node still marked as alive")
+	}
+	if GetConfigBlockchains()["polygon"] || GetClientPool("polygon") != nil {
+		t.Fatal("Removed blockchain still configured")
+	}
+	if blockchainPool.GetNextNode("polygon", nil) != nil {
+		t.Fatal("Node returned for removed blockchain")
+	}
+
+	// Invalid configuration rejected and current one kept
+	if err := ReloadNodes("testdata/nodes_missing.json", false); err == nil {
+		t.Fatal("Expected error for missing configuration")
+	}
+	duplicatesConfigPath := writeConfig(t, "duplicates.txt", "ethereum,10.0.0.5,8545\nethereum,10.0.0.5,8545")
+	if err := ReloadNodes(duplicatesConfigPath, false); err == nil {
+		t.Fatal("Expected error for configuration with duplicated nodes")
+	}
+	// Kept nodes are not changed when new node could not be created
+	defer func(r Resolver) { nodesResolver = r }(nodesResolver)
+	nodesResolver = &fakeResolver{records: map[string][]string{}}
+	unresolvedConfigPath := writeConfig(t, "unresolved.txt", "ethereum,10.0.0.5,8545,5\nethereum,missing.example.com,8545")
+	if err := ReloadNodes(unresolvedConfigPath, false); err == nil {
+		t.Fatal("Expected error for configuration with unresolved node")
+	}
+	if keptNode.GetWeight() != 1 {
+		t.Fatalf("Kept node changed by rejected configuration, weight %d", keptNode.GetWeight())
+	}
+	if GetNodeConfigList().Source != "testdata/nodes_reload.json" {
+		t.Fatalf("Configuration replaced by invalid one: %s", GetNodeConfigList().Source)
+	}
+	if blockchainPool.FindNode("ethereum", "http://10.0.0.7:8545") == nil {
+		t.Fatal("Nodes replaced by invalid configuration")
+	}
+}
+
+func TestNewNodeTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"number":"0x10"}}`))
+	}))
+	defer server.Close()
+	caBundlePath := writeConfig(t, "ca.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+	serverURL, _ := url.Parse(server.URL)
+
+	var cases = []struct {
+		content string
+		alive   bool
+	}{
+		{fmt.Sprintf(`[{"blockchain": "ethereum", "endpoint": "%s"}]`, server.URL), false},
+		{fmt.Sprintf(`[{"blockchain": "ethereum", "endpoint": "%s", "ca_bundle": "%s"}]`, server.URL, caBundlePath), true},
+		{fmt.Sprintf(`[{"blockchain": "ethereum", "endpoint": "%s", "insecure_skip_verify": true}]`, server.URL), true},
+		// WebSocket nodes are checked over HTTP
+		{fmt.Sprintf(`[{"blockchain": "ethereum", "endpoint": "wss://%s", "ca_bundle": "%s"}]`, serverURL.Host, caBundlePath), true},
+	}
+	for i, c := range cases {
+		list, err := ParseNodeConfigs(writeConfig(t, "nodes.json", c.content), true)
+		if err != nil {
+			t.Fatalf("Case %d: unable to parse configuration, err: %v", i, err)
+		}
+		node, err := newNode(list.Nodes[0])
+		if err != nil {
+			t.Fatalf("Case %d: unable to create node, err: %v", i, err)
+		}
+		bpool := BlockchainPool{Blockchains: []*NodePool{{Blockchain: "ethereum", Nodes: []*Node{node}}}}
+		bpool.HealthCheck()
+		if node.IsAlive() != c.alive {
+			t.Fatalf("Case %d: expected node alive %t", i, c.alive)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Validation of configuration without serving.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationSummary describes environment and nodes configuration as it
+// would be loaded by server
+type ValidationSummary struct {
+	Source         string              `json:"source,omitempty"`
+	MalformedLines int                 `json:"malformed_lines"`
+	Blockchains    []BlockchainSummary `json:"blockchains"`
+	Warnings       []string            `json:"warnings"`
+	Errors         []string            `json:"errors"`
+	Valid          bool                `json:"valid"`
+}
+
+// BlockchainSummary describes nodes of blockchain and its health check settings
+type BlockchainSummary struct {
+	Blockchain  string        `json:"blockchain"`
+	NodesNumber int           `json:"nodes_number"`
+	Health      HealthConfig  `json:"health"`
+	Nodes       []NodeSummary `json:"nodes"`
+}
+
+// NodeSummary describes node with settings inherited from blockchain
+type NodeSummary struct {
+	Source     string       `json:"source"`
+	Endpoint   string       `json:"endpoint"`
+	WSEndpoint string       `json:"ws_endpoint,omitempty"`
+	Weight     int          `json:"weight"`
+	Tags       []string     `json:"tags,omitempty"`
+	Health     HealthConfig `json:"health"`
+}
+
+// errorMessages splits aggregated configuration errors to separate messages
+func errorMessages(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if errs, ok := err.(ConfigErrors); ok {
+		return errs
+	}
+	return []string{err.Error()}
+}
+
+// ValidateConfiguration parses and validates nodes configuration with the same
+// functions as server, envErr is result of environment variables loading.
+// Nodes of valid configuration are created as at server start, so node
+// hostnames are resolved.
+func ValidateConfiguration(configPath string, strict bool, envErr error) ValidationSummary {
+	summary := ValidationSummary{
+		Blockchains: []BlockchainSummary{},
+		Warnings:    []string{},
+		Errors:      errorMessages(envErr),
+	}
+
+	list, err := parseNodeConfigSource(configPath, strict)
+	if err != nil {
+		summary.Errors = append(summary.Errors, errorMessages(err)...)
+	} else {
+		warnings, err := list.Validate(strict)
+		summary.Warnings = append(summary.Warnings, warnings...)
+		summary.Errors = append(summary.Errors, errorMessages(err)...)
+		if err == nil {
+			for _, node := range list.Nodes {
+				if _, err := newNode(node); err != nil {
+					summary.Errors = append(summary.Errors, fmt.Sprintf("%s: %v", node.source, err))
+				}
+			}
+		}
+
+		summary.Source = list.Source
+		summary.MalformedLines = list.MalformedLines
+		summary.Blockchains = blockchainSummaries(list)
+	}
+
+	if summary.Errors == nil {
+		summary.Errors = []string{}
+	}
+	summary.Valid = len(summary.Errors) == 0
+	return summary
+}
+
+// blockchainSummaries groups nodes of list by blockchain in order of names
+func blockchainSummaries(list *NodeConfigList) []BlockchainSummary {
+	summaries := make(map[string]*BlockchainSummary)
+	var blockchains []string
+	for i := range list.Nodes {
+		node := &list.Nodes[i]
+		summary, ok := summaries[node.Blockchain]
+		if !ok {
+			summary = &BlockchainSummary{
+				Blockchain: node.Blockchain,
+				Health:     list.EffectiveHealthConfig(node.Blockchain, nil),
+			}
+			summaries[node.Blockchain] = summary
+			blockchains = append(blockchains, node.Blockchain)
+		}
+		summary.NodesNumber++
+		summary.Nodes = append(summary.Nodes, NodeSummary{
+			Source:     node.source,
+			Endpoint:   node.URL(),
+			WSEndpoint: node.WSURL(),
+			Weight:     node.Weight,
+			Tags:       node.Tags,
+			Health:     list.EffectiveHealthConfig(node.Blockchain, node),
+		})
+	}
+	sort.Strings(blockchains)
+
+	result := []BlockchainSummary{}
+	for _, b := range blockchains {
+		result = append(result, *summaries[b])
+	}
+	return result
+}
+
+// healthText describes health check settings in one line
+func healthText(health HealthConfig) string {
+	return fmt.Sprintf(
+		"interval %s, call timeout %s, method %s, unhealthy threshold %d, healthy threshold %d",
+		time.Duration(health.Interval), time.Duration(health.CallTimeout), health.Method,
+		health.UnhealthyThreshold, health.HealthyThreshold,
+	)
+}
+
+// Text returns human readable summary
+func (s ValidationSummary) Text() string {
+	var b strings.Builder
+	if s.Source != "" {
+		fmt.Fprintf(&b, "Nodes configuration %s", s.Source)
+		if s.MalformedLines > 0 {
+			fmt.Fprintf(&b, ", %d malformed lines skipped", s.MalformedLines)
+		}
+		b.WriteString("\n")
+	}
+	for _, blockchain := range s.Blockchains {
+		fmt.Fprintf(&b, "%s: %d nodes, health %s\n", blockchain.Blockchain, blockchain.NodesNumber, healthText(blockchain.Health))
+		for _, node := range blockchain.Nodes {
+			fmt.Fprintf(&b, "    %s weight %d", node.Endpoint, node.Weight)
+			if node.WSEndpoint != "" {
+				fmt.Fprintf(&b, ", websocket %s", node.WSEndpoint)
+			}
+			if len(node.Tags) > 0 {
+				fmt.Fprintf(&b, ", tags %s", strings.Join(node.Tags, ","))
+			}
+			if node.Health != blockchain.Health {
+				fmt.Fprintf(&b, ", health %s", healthText(node.Health))
+			}
+			b.WriteString("\n")
+		}
+	}
+	for _, warning := range s.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", warning)
+	}
+	for _, err := range s.Errors {
+		fmt.Fprintf(&b, "Error: %s\n", err)
+	}
+	if s.Valid {
+		b.WriteString("Configuration is valid\n")
+	} else {
+		fmt.Fprintf(&b, "Configuration is invalid, %d errors found\n", len(s.Errors))
+	}
+	return b.String()
+}
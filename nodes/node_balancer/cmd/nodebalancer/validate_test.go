@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfiguration(t *testing.T) {
+	defer func(config Config) { appConfig = config }(appConfig)
+	appConfig = DefaultConfig()
+	defer func(r Resolver) { nodesResolver = r }(nodesResolver)
+	nodesResolver = &fakeResolver{records: map[string][]string{"node1.example.com": {"127.0.0.1"}}}
+
+	summary := ValidateConfiguration("testdata/nodes_health.yaml", false, nil)
+	if !summary.Valid || len(summary.Errors) != 0 || summary.Source != "testdata/nodes_health.yaml" {
+		t.Fatalf("Expected valid configuration, got %+v", summary)
+	}
+	list, _ := ParseNodeConfigs("testdata/nodes_health.yaml", false)
+	if !reflect.DeepEqual(summary.Blockchains, blockchainSummaries(list)) {
+		t.Fatalf("Wrong blockchains summary: %+v", summary.Blockchains)
+	}
+
+	var cases = []struct {
+		configPath string
+		strict     bool
+		envErr     error
+		valid      bool
+		nodes      map[string]int
+		warnings   int
+		errors     int
+	}{
+		{"testdata/nodes.json", false, nil, true, map[string]int{"ethereum": 2, "polygon": 1}, 0, 0},
+		{"testdata/nodes_comments.txt", false, nil, true, map[string]int{"ethereum": 2, "polygon": 1}, 0, 0},
+		// Malformed lines are errors in strict mode
+		{"testdata/nodes_comments.txt", true, nil, false, map[string]int{}, 0, 1},
+		// Unknown blockchain is warning or error in strict mode
+		{"testdata/nodes_defaults.yaml", false, nil, true, map[string]int{"ethereum": 3, "polygon": 2, "solana": 1, "xdai": 1}, 1, 0},
+		{"testdata/nodes_defaults.yaml", true, nil, false, map[string]int{"ethereum": 3, "polygon": 2, "solana": 1, "xdai": 1}, 0, 1},
+		{"testdata/missing.json", false, nil, false, map[string]int{}, 0, 1},
+		// Hostnames are resolved as at server start
+		{
+			writeConfig(t, "unresolved.txt", "ethereum,10.0.0.5,8545\nethereum,missing.example.com,8545"),
+			false, nil, false, map[string]int{"ethereum": 2}, 0, 1,
+		},
+		// Environment errors are reported with nodes configuration
+		{
+			"testdata/nodes.json", false, ConfigErrors{"NB_SERVER_PORT 0 should be between 1 and 65535", "NB_APPLICATION_ID x should be a UUID"},
+			false, map[string]int{"ethereum": 2, "polygon": 1}, 0, 2,
+		},
+	}
+	for i, c := range cases {
+		summary := ValidateConfiguration(c.configPath, c.strict, c.envErr)
+		if summary.Valid != c.valid || len(summary.Warnings) != c.warnings || len(summary.Errors) != c.errors {
+			t.Fatalf("Case %d: expected valid %t, %d warnings and %d errors, got %+v", i, c.valid, c.warnings, c.errors, summary)
+		}
+		nodes := make(map[string]int)
+		for _, b := range summary.Blockchains {
+			nodes[b.Blockchain] = b.NodesNumber
+		}
+		if !reflect.DeepEqual(nodes, c.nodes) {
+			t.Fatalf("Case %d: expected nodes %v, got %v", i, c.nodes, nodes)
+		}
+
+		text := summary.Text()
+		if c.valid != strings.HasSuffix(text, "Configuration is valid\n") {
+			t.Fatalf("Case %d: wrong text summary %s", i, text)
+		}
+		for _, err := range summary.Errors {
+			if !strings.Contains(text, "Error: "+err) {
+				t.Fatalf("Case %d: expected error %s at text summary", i, err)
+			}
+		}
+	}
+}
+
+func TestValidationSummaryJSON(t *testing.T) {
+	defer func(config Config) { appConfig = config }(appConfig)
+	appConfig = DefaultConfig()
+	defer func(r Resolver) { nodesResolver = r }(nodesResolver)
+	nodesResolver = &fakeResolver{records: map[string][]string{"node1.example.com": {"127.0.0.1"}}}
+
+	summary := ValidateConfiguration("testdata/nodes_ws.yaml", false, nil)
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("Unable to marshal summary, err: %v", err)
+	}
+
+	var decoded struct {
+		Valid       bool     `json:"valid"`
+		Errors      []string `json:"errors"`
+		Blockchains []struct {
+			Blockchain  string `json:"blockchain"`
+			NodesNumber int    `json:"nodes_number"`
+			Health      struct {
+				Interval string `json:"interval"`
+			} `json:"health"`
+			Nodes []struct {
+				Endpoint   string `json:"endpoint"`
+				WSEndpoint string `json:"ws_endpoint"`
+				Weight     int    `json:"weight"`
+			} `json:"nodes"`
+		} `json:"blockchains"`
+	}
+	if err := json.Unmarshal(summaryJSON, &decoded); err != nil {
+		t.Fatalf("Unable to decode summary, err: %v", err)
+	}
+	if !decoded.Valid || decoded.Errors == nil || len(decoded.Blockchains) != 2 {
+		t.Fatalf("Wrong summary: %s", summaryJSON)
+	}
+	ethereum := decoded.Blockchains[0]
+	if ethereum.Blockchain != "ethereum" || ethereum.NodesNumber != 3 || ethereum.Health.Interval != "5s" {
+		t.Fatalf("Wrong ethereum summary: %+v", ethereum)
+	}
+	if ethereum.Nodes[0].WSEndpoint != "ws://10.0.0.5:8546" || ethereum.Nodes[2].WSEndpoint != "" || ethereum.Nodes[2].Weight != 1 {
+		t.Fatalf("Wrong ethereum nodes summary: %+v", ethereum.Nodes)
+	}
+}
@@ -7,4 +7,5 @@ require (
 	github.com/bugout-dev/humbug/go v0.0.0-20211206230955-57607cd2d205
 	github.com/google/uuid v1.3.0
 	github.com/lib/pq v1.10.4
+	gopkg.in/yaml.v3 v3.0.1
 )